@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/defryfazz/fazztalog/config"
+	"github.com/defryfazz/fazztalog/internal/api"
+	"github.com/defryfazz/fazztalog/internal/app"
+	"github.com/defryfazz/fazztalog/internal/database"
+	"github.com/defryfazz/fazztalog/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	cfg := app.LoadConfig(config.SectionEngine, config.SectionDatabase, config.SectionStorage, config.SectionAPI)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// workCtx is cancelled only after the shutdown deadline, so in-flight
+	// generations are not aborted as soon as the signal arrives.
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	db, err := database.OpenSQLite(cfg.SQLitePath)
+	if err != nil {
+		panic(fmt.Sprintf("failed to setup sqlite database: %v", err))
+	}
+	defer db.Close()
+
+	mediaStorage, err := app.SetupStorage(ctx, cfg)
+	if err != nil {
+		panic(fmt.Sprintf("failed to setup storage: %v", err))
+	}
+	go app.NewJanitor(cfg, mediaStorage).Run(workCtx)
+
+	appContainer := app.SetupApp(app.SetupAppParams{
+		DB:      db,
+		Config:  cfg,
+		Storage: mediaStorage,
+	})
+
+	brochureWorker := api.NewBrochureWorker(appContainer.MerchantService, appContainer.JobService, cfg.API.BrochureQueueSize, cfg.API.BrochureWorkers)
+	brochureWorker.Start(workCtx)
+	metrics.RegisterQueueDepth(brochureWorker.QueueDepth)
+	if err := api.ResumeBrochureJobs(workCtx, appContainer.JobService, brochureWorker); err != nil {
+		log.Error().Err(err).Msg("error resuming brochure jobs")
+	}
+
+	server := &http.Server{
+		Addr: cfg.API.ListenAddr,
+		Handler: api.NewRouter(api.RouterParams{
+			MerchantService: appContainer.MerchantService,
+			JobService:      appContainer.JobService,
+			DeviceService:   appContainer.DeviceService,
+			BrochureWorker:  brochureWorker,
+			Storage:         appContainer.Storage,
+			Health:          app.NewHealthChecker(db, appContainer.AIEngine, appContainer.Storage, cfg.HealthCheckTimeout),
+			AdminToken:      cfg.AdminToken,
+			APIToken:        cfg.API.Token,
+		}),
+	}
+	go func() {
+		log.Info().Str("addr", cfg.API.ListenAddr).Msg("API server is listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(fmt.Sprintf("failed to serve api: %v", err))
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info().Msg("shutting down API server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("error shutting down api server")
+	}
+	if err := brochureWorker.Shutdown(shutdownCtx); err != nil {
+		log.Warn().Err(err).Dur("timeout", cfg.ShutdownTimeout).Msg("brochure jobs did not finish in time, cancelling them")
+		cancelWork()
+		brochureWorker.Wait()
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/defryfazz/fazztalog/config"
+	"github.com/defryfazz/fazztalog/internal/app"
+	"github.com/defryfazz/fazztalog/internal/database"
+	"github.com/defryfazz/fazztalog/internal/sheet"
+)
+
+// sheetimport syncs merchant catalogs from their Google Sheets. Every linked
+// merchant is synced unless -merchant picks one.
+func main() {
+	merchantID := flag.String("merchant", "", "ID of the only merchant to sync")
+	cfg := app.LoadConfig(config.SectionDatabase, config.SectionStorage, config.SectionSheet)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.OpenSQLite(cfg.SQLitePath)
+	if err != nil {
+		panic(fmt.Sprintf("failed to setup sqlite database: %v", err))
+	}
+	defer db.Close()
+
+	mediaStorage, err := app.SetupStorage(ctx, cfg)
+	if err != nil {
+		panic(fmt.Sprintf("failed to setup storage: %v", err))
+	}
+
+	appContainer := app.SetupApp(app.SetupAppParams{
+		DB:      db,
+		Config:  cfg,
+		Storage: mediaStorage,
+	})
+
+	if *merchantID != "" {
+		m, err := appContainer.MerchantService.GetMerchant(ctx, *merchantID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error getting merchant: %v\n", err)
+			os.Exit(1)
+		}
+		sync, err := appContainer.SheetService.SyncMerchant(ctx, *m)
+		if sync != nil {
+			printSync(*sync)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "error syncing %s (%s): %v\n", m.Name, m.Phone, err)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	res, err := appContainer.SheetService.SyncAll(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error syncing sheet: %v\n", err)
+		os.Exit(1)
+	}
+	failed := res.SharedErr != nil
+	if res.SharedErr != nil {
+		fmt.Printf("shared sheet: error: %v\n", res.SharedErr)
+	}
+	for _, sync := range res.Merchants {
+		printSync(sync)
+		if sync.Err != nil {
+			failed = true
+		}
+	}
+	for _, userID := range res.UnknownUsers {
+		fmt.Printf("user_id %s: no merchant with this phone, rows skipped\n", userID)
+	}
+	fmt.Printf("%d merchants synced, %d unknown user_ids\n", len(res.Merchants), len(res.UnknownUsers))
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printSync(sync sheet.MerchantSync) {
+	m, res := sync.Merchant, sync.Result
+	switch {
+	case sync.Err != nil:
+		fmt.Printf("%s (%s): error: %v\n", m.Name, m.Phone, sync.Err)
+	case res != nil:
+		fmt.Printf("%s (%s): %d added, %d updated, %d removed, %d kept, %d unchanged\n",
+			m.Name, m.Phone, len(res.Added), len(res.Updated), len(res.Removed), len(res.Kept), res.Unchanged)
+	}
+	for _, row := range sync.Skipped {
+		fmt.Printf("  row %d skipped: %s\n", row.Row, row.Reason)
+	}
+}
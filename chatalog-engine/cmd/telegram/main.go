@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/defryfazz/fazztalog/config"
+	"github.com/defryfazz/fazztalog/internal/app"
+	"github.com/defryfazz/fazztalog/internal/channel/telegram"
+	"github.com/defryfazz/fazztalog/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	cfg := app.LoadConfig(config.SectionEngine, config.SectionDatabase, config.SectionStorage, config.SectionTelegram)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// workCtx is cancelled only after the shutdown deadline, so in-flight
+	// generations are not aborted as soon as the signal arrives.
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	db, err := database.OpenSQLite(cfg.SQLitePath)
+	if err != nil {
+		panic(fmt.Sprintf("failed to setup sqlite database: %v", err))
+	}
+	defer db.Close()
+
+	mediaStorage, err := app.SetupStorage(ctx, cfg)
+	if err != nil {
+		panic(fmt.Sprintf("failed to setup storage: %v", err))
+	}
+	go app.NewJanitor(cfg, mediaStorage).Run(workCtx)
+
+	appContainer := app.SetupApp(app.SetupAppParams{
+		DB:      db,
+		Config:  cfg,
+		Storage: mediaStorage,
+	})
+	conversationHandler := appContainer.Conversation
+	client := telegram.NewClient(cfg.Telegram.BotToken, cfg.Telegram.APIURL)
+	adapter := telegram.NewAdapter(client)
+	conversationHandler.RegisterMessenger(adapter)
+
+	if err := conversationHandler.ResumeJobs(workCtx); err != nil {
+		log.Error().Err(err).Msg("error resuming brochure jobs")
+	}
+
+	mux := http.NewServeMux()
+	app.NewHealthChecker(db, appContainer.AIEngine, appContainer.Storage, cfg.HealthCheckTimeout).Register(mux)
+	if cfg.Telegram.WebhookURL != "" {
+		if err := client.SetWebhook(ctx, cfg.Telegram.WebhookURL, cfg.Telegram.WebhookSecret); err != nil {
+			panic(fmt.Sprintf("failed to set telegram webhook: %v", err))
+		}
+		mux.Handle("/", adapter.WebhookHandler(workCtx, conversationHandler, cfg.Telegram.WebhookSecret))
+	}
+
+	server := &http.Server{
+		Addr:    cfg.Telegram.ListenAddr,
+		Handler: mux,
+	}
+	go func() {
+		log.Info().Str("addr", cfg.Telegram.ListenAddr).Msg("Telegram server is listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(fmt.Sprintf("failed to serve telegram server: %v", err))
+		}
+	}()
+
+	if cfg.Telegram.WebhookURL != "" {
+		<-ctx.Done()
+	} else {
+		log.Info().Msg("Telegram bot is polling for updates")
+		if err := adapter.Poll(ctx, workCtx, conversationHandler); err != nil {
+			panic(fmt.Sprintf("failed to poll telegram updates: %v", err))
+		}
+	}
+
+	log.Info().Msg("shutting down, waiting for in-flight work to finish")
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("error shutting down telegram server")
+	}
+	if err := conversationHandler.Shutdown(shutdownCtx); err != nil {
+		log.Warn().Err(err).Dur("timeout", cfg.ShutdownTimeout).Msg("in-flight work did not finish in time, cancelling it")
+		cancelWork()
+		conversationHandler.Wait()
+	}
+}
@@ -7,13 +7,16 @@ import (
 	"github.com/defryfazz/fazztalog/config"
 	"github.com/defryfazz/fazztalog/internal/ai"
 	"github.com/defryfazz/fazztalog/internal/ai/engine"
+	"github.com/defryfazz/fazztalog/internal/app"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 )
 
 func main() {
-	client := openai.NewClient(option.WithAPIKey(config.OpenAIToken))
-	eng := engine.NewOpenAIEngine(client, config.TempFolderPath)
+	cfg := app.LoadConfig(config.SectionEngine)
+
+	client := openai.NewClient(option.WithAPIKey(cfg.OpenAIToken))
+	eng := engine.NewOpenAIEngine(client, cfg.TempFolderPath)
 
 	res, err := eng.GenerateBrochure(context.Background(), ai.BrochureDetails{
 		MerchantName: "Fazz Coffee",
@@ -26,5 +29,5 @@ func main() {
 		panic(err)
 	}
 
-	fmt.Println(res)
+	fmt.Println(res.FilePath)
 }
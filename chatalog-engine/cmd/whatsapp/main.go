@@ -2,38 +2,105 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/defryfazz/fazztalog/config"
 	"github.com/defryfazz/fazztalog/internal/app"
+	"github.com/defryfazz/fazztalog/internal/channel/whatsapp"
+	"github.com/defryfazz/fazztalog/internal/database"
+	"github.com/rs/zerolog/log"
 )
 
 func main() {
+	cfg := app.LoadConfig(config.SectionEngine, config.SectionDatabase, config.SectionStorage, config.SectionWhatsApp)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	client, err := setupWhatsmeowClient(ctx)
+	// workCtx is cancelled only after the shutdown deadline, so in-flight
+	// generations are not aborted as soon as the signal arrives.
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	container, err := setupWhatsmeowContainer(ctx, cfg.WhatsApp.SQLPath)
 	if err != nil {
-		panic(fmt.Sprintf("failed to setup whatsmeow client: %v", err))
+		panic(fmt.Sprintf("failed to setup whatsmeow container: %v", err))
 	}
 
-	db, err := setupSQLiteDatabase(config.SQLitePath)
+	db, err := database.OpenSQLite(cfg.SQLitePath)
 	if err != nil {
 		panic(fmt.Sprintf("failed to setup sqlite database: %v", err))
 	}
+	defer db.Close()
+
+	mediaStorage, err := app.SetupStorage(ctx, cfg)
+	if err != nil {
+		panic(fmt.Sprintf("failed to setup storage: %v", err))
+	}
+	go app.NewJanitor(cfg, mediaStorage).Run(workCtx)
 
 	appContainer := app.SetupApp(app.SetupAppParams{
-		OpenAIToken:   config.OpenAIToken,
-		DB:            db,
-		TempDirectory: config.TempFolderPath,
+		DB:      db,
+		Config:  cfg,
+		Storage: mediaStorage,
 	})
-	eventHandler := &EventHandler{
-		client:       client,
-		appContainer: appContainer,
+	conversationHandler := appContainer.Conversation
+	manager := whatsapp.NewManager(workCtx, whatsapp.ManagerParams{
+		Container: container,
+		Handler:   conversationHandler,
+		Registry:  conversationHandler,
+	})
+
+	checker := app.NewHealthChecker(db, appContainer.AIEngine, appContainer.Storage, cfg.HealthCheckTimeout)
+	checker.Add("whatsapp", func(ctx context.Context) error {
+		return manager.Ready()
+	})
+
+	mux := http.NewServeMux()
+	adminHandler := manager.AdminHandler(cfg.AdminToken)
+	mux.Handle("/devices", adminHandler)
+	mux.Handle("/devices/", adminHandler)
+	checker.Register(mux)
+
+	adminServer := &http.Server{
+		Addr:    cfg.WhatsApp.AdminListenAddr,
+		Handler: mux,
+	}
+	go func() {
+		log.Info().Str("addr", adminServer.Addr).Msg("WhatsApp device admin is listening")
+		if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("error serving device admin")
+		}
+	}()
+
+	if err := connectWhatsmeowDevices(ctx, manager, cfg.WhatsApp.AdminListenAddr); err != nil {
+		panic(fmt.Sprintf("failed to connect whatsmeow devices: %v", err))
+	}
+
+	if err := conversationHandler.ResumeJobs(workCtx); err != nil {
+		log.Error().Err(err).Msg("error resuming brochure jobs")
 	}
-	client.AddEventHandler(eventHandler.Handle(ctx))
+	go app.NewSheetScheduler(cfg, appContainer).Run(workCtx)
+
+	<-ctx.Done()
+	log.Info().Msg("shutting down, waiting for in-flight work to finish")
 
-	connectWhatsmeowClient(client)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := adminServer.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("error shutting down device admin")
+	}
+	if err := conversationHandler.Shutdown(shutdownCtx); err != nil {
+		log.Warn().Err(err).Dur("timeout", cfg.ShutdownTimeout).Msg("in-flight work did not finish in time, cancelling it")
+		cancelWork()
+		conversationHandler.Wait()
+	}
 
+	log.Info().Msg("WhatsApp devices disconnected")
+	manager.Disconnect()
 }
@@ -3,60 +3,46 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
-	"os/signal"
-	"syscall"
 
-	"github.com/defryfazz/fazztalog/config"
+	"github.com/defryfazz/fazztalog/internal/channel/whatsapp"
 	_ "github.com/mattn/go-sqlite3"
-	"go.mau.fi/whatsmeow"
+	"github.com/rs/zerolog/log"
 	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"google.golang.org/protobuf/proto"
 )
 
-func setupWhatsmeowClient(ctx context.Context) (*whatsmeow.Client, error) {
-	container, err := sqlstore.New(ctx, "sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", config.WhatsmeowSQLPath), nil)
+func setupWhatsmeowContainer(ctx context.Context, sqlPath string) (*sqlstore.Container, error) {
+	container, err := sqlstore.New(ctx, "sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", sqlPath), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing sqlite: %v", err)
 	}
-	deviceStore, err := container.GetFirstDevice(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("error getting device: %v", err)
-	}
-	client := whatsmeow.NewClient(deviceStore, nil)
-	return client, nil
+	return container, nil
 }
 
-func connectWhatsmeowClient(client *whatsmeow.Client) {
+// connectWhatsmeowDevices connects every stored device. On the first start
+// there is no device yet, so one is added to be paired through the admin.
+func connectWhatsmeowDevices(ctx context.Context, manager *whatsapp.Manager, adminListenAddr string) error {
 	store.DeviceProps.Os = proto.String("chatalog")
-	if client.Store.ID == nil {
-		qrChan, _ := client.GetQRChannel(context.Background())
-		err := client.Connect()
-		if err != nil {
-			panic(err)
-		}
-		for evt := range qrChan {
-			if evt.Event == "code" {
-				log.Println("QR code:", evt.Code)
-			} else {
-				log.Println("Login event:", evt.Event)
-			}
-		}
-	} else {
-		err := client.Connect()
-		if err != nil {
-			panic(err)
-		}
+	if err := manager.LoadDevices(ctx); err != nil {
+		return err
 	}
 
-	log.Println("WhatsApp Client has connected")
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	<-c
+	if len(manager.Devices()) == 0 {
+		if _, err := manager.AddDevice(); err != nil {
+			return err
+		}
+	}
 
-	log.Println("WhatsApp Client disconnected")
-	client.Disconnect()
+	for _, d := range manager.Devices() {
+		if d.Login.Status().State == whatsapp.LoginStateLoggedIn {
+			log.Info().Str("device_id", d.ID).Msg("WhatsApp device has connected")
+		} else {
+			log.Info().
+				Str("device_id", d.ID).
+				Str("login_url", fmt.Sprintf("http://%s/devices/%s/login?token=<ADMIN_TOKEN>", adminListenAddr, d.ID)).
+				Msg("WhatsApp device is not paired, open the login URL to log in")
+		}
+	}
+	return nil
 }
@@ -1,32 +1,262 @@
 package config
 
 import (
-	"log"
-	"sync"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/logger"
+)
+
+// Config holds the settings of every command. Each field has a key used in
+// config files, nested under the key of its section, and the environment
+// variable that overrides it. Fields tagged secret are never logged.
+type Config struct {
+	TempFolderPath     string        `key:"temp_folder_path" env:"TEMP_FOLDER_PATH"`
+	SQLitePath         string        `key:"sqlite_path" env:"SQLITE_PATH"`
+	OpenAIToken        string        `key:"openai_token" env:"OPEN_AI_TOKEN" secret:"true"`
+	AdminToken         string        `key:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+	ShutdownTimeout    time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	HealthCheckTimeout time.Duration `key:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+
+	Log           LogConfig           `key:"log"`
+	WhatsApp      WhatsAppConfig      `key:"whatsapp"`
+	Telegram      TelegramConfig      `key:"telegram"`
+	API           APIConfig           `key:"api"`
+	WhatsAppCloud WhatsAppCloudConfig `key:"whatsapp_cloud"`
+	Storage       StorageConfig       `key:"storage"`
+	Sheet         SheetConfig         `key:"sheet"`
+}
+
+type LogConfig struct {
+	Level  string `key:"level" env:"LOG_LEVEL"`
+	Format string `key:"format" env:"LOG_FORMAT"`
+}
+
+type WhatsAppConfig struct {
+	SQLPath         string `key:"sql_path" env:"WHATSMEOW_SQL_PATH"`
+	AdminListenAddr string `key:"admin_listen_addr" env:"WHATSAPP_ADMIN_LISTEN_ADDR"`
+}
+
+type TelegramConfig struct {
+	BotToken      string `key:"bot_token" env:"TELEGRAM_BOT_TOKEN" secret:"true"`
+	APIURL        string `key:"api_url" env:"TELEGRAM_API_URL"`
+	WebhookURL    string `key:"webhook_url" env:"TELEGRAM_WEBHOOK_URL"`
+	WebhookSecret string `key:"webhook_secret" env:"TELEGRAM_WEBHOOK_SECRET" secret:"true"`
+	ListenAddr    string `key:"listen_addr" env:"TELEGRAM_LISTEN_ADDR"`
+}
+
+type APIConfig struct {
+	ListenAddr string `key:"listen_addr" env:"API_LISTEN_ADDR"`
+	// Token authorizes the web frontend and partner systems to generate
+	// brochures. It does not give access to the admin endpoints.
+	Token             string `key:"token" env:"API_TOKEN" secret:"true"`
+	BrochureQueueSize int    `key:"brochure_queue_size" env:"BROCHURE_QUEUE_SIZE"`
+	BrochureWorkers   int    `key:"brochure_workers" env:"BROCHURE_WORKERS"`
+}
+
+type WhatsAppCloudConfig struct {
+	GraphURL      string `key:"graph_url" env:"WHATSAPP_CLOUD_GRAPH_URL"`
+	APIVersion    string `key:"api_version" env:"WHATSAPP_CLOUD_API_VERSION"`
+	AccessToken   string `key:"access_token" env:"WHATSAPP_CLOUD_ACCESS_TOKEN" secret:"true"`
+	PhoneNumberID string `key:"phone_number_id" env:"WHATSAPP_CLOUD_PHONE_NUMBER_ID"`
+	VerifyToken   string `key:"verify_token" env:"WHATSAPP_CLOUD_VERIFY_TOKEN" secret:"true"`
+	AppSecret     string `key:"app_secret" env:"WHATSAPP_CLOUD_APP_SECRET" secret:"true"`
+	ListenAddr    string `key:"listen_addr" env:"WHATSAPP_CLOUD_LISTEN_ADDR"`
+}
+
+type StorageConfig struct {
+	// Backend is where brochures, product photos and logos are kept, either
+	// local or s3.
+	Backend string `key:"backend" env:"STORAGE_BACKEND"`
+	// LocalPath is the root of the local backend. It defaults to the media
+	// directory inside the temp folder.
+	LocalPath   string `key:"local_path" env:"STORAGE_LOCAL_PATH"`
+	S3Endpoint  string `key:"s3_endpoint" env:"STORAGE_S3_ENDPOINT"`
+	S3Region    string `key:"s3_region" env:"STORAGE_S3_REGION"`
+	S3Bucket    string `key:"s3_bucket" env:"STORAGE_S3_BUCKET"`
+	S3AccessKey string `key:"s3_access_key" env:"STORAGE_S3_ACCESS_KEY"`
+	S3SecretKey string `key:"s3_secret_key" env:"STORAGE_S3_SECRET_KEY" secret:"true"`
+	S3UseSSL    bool   `key:"s3_use_ssl" env:"STORAGE_S3_USE_SSL"`
+	// BrochureRetention is how long generated brochures are kept. Brochures are
+	// kept forever when it is zero.
+	BrochureRetention time.Duration `key:"brochure_retention" env:"STORAGE_BROCHURE_RETENTION"`
+	// TempRetention is how long files are kept in the temp folder.
+	TempRetention   time.Duration `key:"temp_retention" env:"STORAGE_TEMP_RETENTION"`
+	JanitorInterval time.Duration `key:"janitor_interval" env:"STORAGE_JANITOR_INTERVAL"`
+}
+
+type SheetConfig struct {
+	// ServerCommand runs the mcp-sheet-go server, e.g. /usr/local/bin/mcp-sheet.
+	// Arguments are separated by spaces.
+	ServerCommand string `key:"server_command" env:"SHEET_SERVER_COMMAND"`
+	// URL is the CSV export URL of the shared Google Sheet, used by merchants
+	// without their own sheet. Rows belong to the merchant whose phone is in
+	// the user_id column.
+	URL     string        `key:"url" env:"SHEET_URL"`
+	Timeout time.Duration `key:"timeout" env:"SHEET_TIMEOUT"`
+	// SyncInterval is the time between scheduled syncs of every linked sheet
+	// by the WhatsApp processes. Scheduled syncs are off when it is zero.
+	SyncInterval time.Duration `key:"sync_interval" env:"SHEET_SYNC_INTERVAL"`
+}
+
+const (
+	StorageBackendLocal = "local"
+	StorageBackendS3    = "s3"
 )
 
-var (
-	once sync.Once
+// Default returns the configuration used for settings that are neither in the
+// config file nor in the environment.
+func Default() Config {
+	return Config{
+		ShutdownTimeout:    30 * time.Second,
+		HealthCheckTimeout: 5 * time.Second,
+		Log: LogConfig{
+			Level:  "info",
+			Format: logger.FormatJSON,
+		},
+		WhatsApp: WhatsAppConfig{
+			AdminListenAddr: ":8083",
+		},
+		Telegram: TelegramConfig{
+			APIURL:     "https://api.telegram.org",
+			ListenAddr: ":8081",
+		},
+		API: APIConfig{
+			ListenAddr:        ":8080",
+			BrochureQueueSize: 100,
+			BrochureWorkers:   2,
+		},
+		WhatsAppCloud: WhatsAppCloudConfig{
+			GraphURL:   "https://graph.facebook.com",
+			APIVersion: "v21.0",
+			ListenAddr: ":8082",
+		},
+		Storage: StorageConfig{
+			Backend:         StorageBackendLocal,
+			S3UseSSL:        true,
+			TempRetention:   24 * time.Hour,
+			JanitorInterval: time.Hour,
+		},
+		Sheet: SheetConfig{
+			Timeout: time.Minute,
+		},
+	}
+}
 
-	TempFolderPath   string
-	WhatsmeowSQLPath string
-	SQLitePath       string
+// Section names a group of settings a command depends on. Load only requires
+// the settings of the sections the command asks for.
+type Section string
 
-	OpenAIToken string
+const (
+	SectionEngine        Section = "engine"
+	SectionDatabase      Section = "database"
+	SectionWhatsApp      Section = "whatsapp"
+	SectionTelegram      Section = "telegram"
+	SectionAPI           Section = "api"
+	SectionWhatsAppCloud Section = "whatsapp_cloud"
+	SectionStorage       Section = "storage"
+	SectionSheet         Section = "sheet"
 )
 
-func init() {
-	once.Do(func() {
-		TempFolderPath = getString("TEMP_FOLDER_PATH", "")
-		WhatsmeowSQLPath = getString("WHATSMEOW_SQL_PATH", "")
-		SQLitePath = getString("SQLITE_PATH", "")
+// Validate checks the settings shared by every command and the settings of the
+// given sections. All problems are reported at once.
+func (c *Config) Validate(sections ...Section) error {
+	var errs []error
+	require := func(value string, key string, env string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s (%s) is required", key, env))
+		}
+	}
+	positive := func(value int64, key string, env string) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s (%s) must be positive", key, env))
+		}
+	}
 
-		OpenAIToken = getString("OPEN_AI_TOKEN", "")
+	positive(int64(c.ShutdownTimeout), "shutdown_timeout", "SHUTDOWN_TIMEOUT")
+	positive(int64(c.HealthCheckTimeout), "health_check_timeout", "HEALTH_CHECK_TIMEOUT")
+	if c.Log.Format != logger.FormatJSON && c.Log.Format != logger.FormatConsole {
+		errs = append(errs, fmt.Errorf("log.format (LOG_FORMAT) must be json or console, got %q", c.Log.Format))
+	}
+	// Sheet sync is optional for the chat processes, its settings are checked
+	// whenever they are set.
+	if c.Sheet.URL != "" {
+		if err := validateURL(c.Sheet.URL); err != nil {
+			errs = append(errs, fmt.Errorf("sheet.url (SHEET_URL): %w", err))
+		}
+	}
+	if c.Sheet.SyncInterval < 0 {
+		errs = append(errs, errors.New("sheet.sync_interval (SHEET_SYNC_INTERVAL) must not be negative"))
+	}
+
+	for _, section := range sections {
+		switch section {
+		case SectionEngine:
+			require(c.OpenAIToken, "openai_token", "OPEN_AI_TOKEN")
+			require(c.TempFolderPath, "temp_folder_path", "TEMP_FOLDER_PATH")
+		case SectionDatabase:
+			require(c.SQLitePath, "sqlite_path", "SQLITE_PATH")
+		case SectionWhatsApp:
+			require(c.WhatsApp.SQLPath, "whatsapp.sql_path", "WHATSMEOW_SQL_PATH")
+			require(c.AdminToken, "admin_token", "ADMIN_TOKEN")
+		case SectionTelegram:
+			require(c.Telegram.BotToken, "telegram.bot_token", "TELEGRAM_BOT_TOKEN")
+			if err := validateURL(c.Telegram.APIURL); err != nil {
+				errs = append(errs, fmt.Errorf("telegram.api_url (TELEGRAM_API_URL): %w", err))
+			}
+			if c.Telegram.WebhookURL != "" {
+				if err := validateURL(c.Telegram.WebhookURL); err != nil {
+					errs = append(errs, fmt.Errorf("telegram.webhook_url (TELEGRAM_WEBHOOK_URL): %w", err))
+				}
+			}
+		case SectionAPI:
+			require(c.AdminToken, "admin_token", "ADMIN_TOKEN")
+			positive(int64(c.API.BrochureQueueSize), "api.brochure_queue_size", "BROCHURE_QUEUE_SIZE")
+			positive(int64(c.API.BrochureWorkers), "api.brochure_workers", "BROCHURE_WORKERS")
+		case SectionWhatsAppCloud:
+			require(c.WhatsAppCloud.AccessToken, "whatsapp_cloud.access_token", "WHATSAPP_CLOUD_ACCESS_TOKEN")
+			require(c.WhatsAppCloud.PhoneNumberID, "whatsapp_cloud.phone_number_id", "WHATSAPP_CLOUD_PHONE_NUMBER_ID")
+			require(c.WhatsAppCloud.VerifyToken, "whatsapp_cloud.verify_token", "WHATSAPP_CLOUD_VERIFY_TOKEN")
+			require(c.WhatsAppCloud.AppSecret, "whatsapp_cloud.app_secret", "WHATSAPP_CLOUD_APP_SECRET")
+			if err := validateURL(c.WhatsAppCloud.GraphURL); err != nil {
+				errs = append(errs, fmt.Errorf("whatsapp_cloud.graph_url (WHATSAPP_CLOUD_GRAPH_URL): %w", err))
+			}
+		case SectionStorage:
+			switch c.Storage.Backend {
+			case StorageBackendLocal:
+			case StorageBackendS3:
+				require(c.Storage.S3Endpoint, "storage.s3_endpoint", "STORAGE_S3_ENDPOINT")
+				require(c.Storage.S3Bucket, "storage.s3_bucket", "STORAGE_S3_BUCKET")
+				require(c.Storage.S3AccessKey, "storage.s3_access_key", "STORAGE_S3_ACCESS_KEY")
+				require(c.Storage.S3SecretKey, "storage.s3_secret_key", "STORAGE_S3_SECRET_KEY")
+			default:
+				errs = append(errs, fmt.Errorf("storage.backend (STORAGE_BACKEND) must be local or s3, got %q", c.Storage.Backend))
+			}
+			if c.Storage.BrochureRetention < 0 {
+				errs = append(errs, errors.New("storage.brochure_retention (STORAGE_BROCHURE_RETENTION) must not be negative"))
+			}
+			positive(int64(c.Storage.TempRetention), "storage.temp_retention", "STORAGE_TEMP_RETENTION")
+			positive(int64(c.Storage.JanitorInterval), "storage.janitor_interval", "STORAGE_JANITOR_INTERVAL")
+		case SectionSheet:
+			require(c.Sheet.ServerCommand, "sheet.server_command", "SHEET_SERVER_COMMAND")
+			positive(int64(c.Sheet.Timeout), "sheet.timeout", "SHEET_TIMEOUT")
+		default:
+			errs = append(errs, fmt.Errorf("unknown config section %q", section))
+		}
+	}
+
+	return errors.Join(errs...)
+}
 
-		log.Println("Configuration loaded")
-		log.Printf("TempFolderPath: %s\n", TempFolderPath)
-		log.Printf("WhatsmeowSQLPath: %s\n", WhatsmeowSQLPath)
-		log.Printf("SQLitePath: %s\n", SQLitePath)
-		log.Printf("OpenAIToken: %s\n", OpenAIToken)
-	})
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("must be an absolute http(s) URL, got %q", raw)
+	}
+	return nil
 }
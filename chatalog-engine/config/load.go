@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Load builds the configuration from the defaults, the optional config file at
+// path and the environment, in increasing order of precedence, and validates
+// it for the given sections. The file is parsed as YAML or TOML depending on
+// its extension.
+func Load(path string, sections ...Section) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file %s: %w", path, err)
+		}
+		if err := applyFile(&cfg, values); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(sections...); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+	return &cfg, nil
+}
+
+// Secrets returns the values of the secret settings that are set, so they can
+// be redacted from the logs.
+func (c *Config) Secrets() []string {
+	var res []string
+	walk(reflect.ValueOf(c).Elem(), "", func(f field) error {
+		if f.secret && f.value.String() != "" {
+			res = append(res, f.value.String())
+		}
+		return nil
+	})
+	return res
+}
+
+// Summary returns every setting by key for logging. Secret settings only
+// report whether they are set.
+func (c *Config) Summary() map[string]string {
+	res := map[string]string{}
+	walk(reflect.ValueOf(c).Elem(), "", func(f field) error {
+		if f.secret {
+			res[f.key+"_set"] = strconv.FormatBool(f.value.String() != "")
+			return nil
+		}
+		res[f.key] = fmt.Sprint(f.value.Interface())
+		return nil
+	})
+	return res
+}
+
+type field struct {
+	key    string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walk calls fn for every setting of the struct v, descending into sections.
+func walk(v reflect.Value, prefix string, fn func(f field) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := prefix + sf.Tag.Get("key")
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			if err := walk(v.Field(i), key+".", fn); err != nil {
+				return err
+			}
+			continue
+		}
+		err := fn(field{
+			key:    key,
+			env:    sf.Tag.Get("env"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use a value like 30s or 5m", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, use true or false", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func applyEnv(cfg *Config) error {
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), "", func(f field) error {
+		raw, ok := os.LookupEnv(f.env)
+		if !ok || raw == "" {
+			return nil
+		}
+		if err := setValue(f.value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+func readFile(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &values)
+	case ".toml":
+		err = toml.Unmarshal(b, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q, use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// applyFile sets the settings found in the parsed config file. Unknown keys are
+// rejected so a typo does not silently fall back to the default.
+func applyFile(cfg *Config, values map[string]any) error {
+	flat := map[string]any{}
+	flatten(values, "", flat)
+
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), "", func(f field) error {
+		raw, ok := flat[f.key]
+		if !ok {
+			return nil
+		}
+		delete(flat, f.key)
+		if raw == nil {
+			return nil
+		}
+		if err := setValue(f.value, fmt.Sprint(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.key, err))
+		}
+		return nil
+	})
+
+	unknown := make([]string, 0, len(flat))
+	for key := range flat {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("unknown key %s", key))
+	}
+	return errors.Join(errs...)
+}
+
+func flatten(values map[string]any, prefix string, res map[string]any) {
+	for key, value := range values {
+		if nested, ok := value.(map[string]any); ok {
+			flatten(nested, prefix+key+".", res)
+			continue
+		}
+		res[prefix+key] = value
+	}
+}
@@ -5,4 +5,17 @@ type Intent string
 const (
 	IntentUnknown            Intent = "unknown"
 	IntentBrochureGeneration Intent = "brochure_generation"
+	IntentBrochureResend     Intent = "brochure_resend"
+	IntentBrochureList       Intent = "brochure_list"
+	IntentSheetSync          Intent = "sheet_sync"
+)
+
+// Period is the time range a user asks about, e.g. when listing brochures.
+type Period string
+
+const (
+	PeriodAll       Period = "all"
+	PeriodToday     Period = "today"
+	PeriodThisWeek  Period = "this_week"
+	PeriodThisMonth Period = "this_month"
 )
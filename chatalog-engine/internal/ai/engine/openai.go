@@ -6,15 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/defryfazz/fazztalog/internal/ai"
+	"github.com/defryfazz/fazztalog/internal/metrics"
 	"github.com/google/uuid"
 	"github.com/openai/openai-go"
+	"github.com/rs/zerolog"
 )
 
 type OpenAIEngine struct {
@@ -29,11 +31,21 @@ func NewOpenAIEngine(client openai.Client, tempDir string) *OpenAIEngine {
 	}
 }
 
+// Ping checks that the OpenAI API is reachable with the configured token.
+func (e *OpenAIEngine) Ping(ctx context.Context) error {
+	start := time.Now()
+	_, err := e.client.Models.Get(ctx, openai.ChatModelGPT4o)
+	observe(ctx, "ping", start, err)
+	return err
+}
+
 func (e *OpenAIEngine) TranscribeAudio(ctx context.Context, file io.Reader) (string, error) {
+	start := time.Now()
 	res, err := e.client.Audio.Transcriptions.New(ctx, openai.AudioTranscriptionNewParams{
 		Model: openai.AudioModelWhisper1,
 		File:  file,
 	})
+	observe(ctx, "transcription", start, err)
 	if err != nil {
 		return "", err
 	}
@@ -45,13 +57,17 @@ func (e *OpenAIEngine) DetermineIntent(ctx context.Context, message string) (*ai
 	prompt := `
 		You are an assistant that extracts user intent from input.
 		There are several intents available:
-		- %s: Brochure generation. This intent is used when the user wants to create a brochure for a product or service.
-		- %s: Unknown. This intent is used when the user's intent is not listed in available list.
+		- %[1]s: Brochure generation. This intent is used when the user wants to create a brochure for a product or service.
+		- %[2]s: Brochure resend. This intent is used when the user wants to receive their last generated brochure again.
+		- %[3]s: Brochure list. This intent is used when the user wants to see the brochures they generated before.
+		- %[4]s: Sheet sync. This intent is used when the user wants to update their product catalog from their Google Sheet.
+		- %[5]s: Unknown. This intent is used when the user's intent is not listed in available list.
 
 		IMPORTANT:
 		- If the user input does not match any of the available intents, you must choose "unknown".
 		- You must only choose one from the available intents.
 		- If the intent is brochure generation, you must get the product's names from the message. If there is no product, just return an empty list.
+		- If the intent is brochure list, you must get the period from the message. The period is one of "%[6]s", "%[7]s", "%[8]s" or "%[9]s". If there is no period, choose "%[6]s".
 
 		Based on the user input, determine the user's intent from the available list. Remember to only choose one from the available intents. If the user's intent is not listed, choose "unknown".
 		Always return with correct JSON format without any \n or \t
@@ -61,25 +77,45 @@ func (e *OpenAIEngine) DetermineIntent(ctx context.Context, message string) (*ai
 		  Output: {"intent": "brochure_generation","products": []}
 		- Input: Please help to create a brochure for Fried Chicken and Coke.
 		  Output: {"intent": "brochure_generation","products": ["Fried Chicken", "Coke"]}
+		- Input: Send my last brochure again.
+		  Output: {"intent": "brochure_resend","products": []}
+		- Input: Show my brochures this week.
+		  Output: {"intent": "brochure_list","products": [],"period": "this_week"}
+		- Input: Sync my sheet.
+		  Output: {"intent": "sheet_sync","products": []}
 		- Input: Hello, how are you?
 		  Output: {"intent": "unknown","products": []}
 	`
-
+	prompt = fmt.Sprintf(prompt,
+		ai.IntentBrochureGeneration,
+		ai.IntentBrochureResend,
+		ai.IntentBrochureList,
+		ai.IntentSheetSync,
+		ai.IntentUnknown,
+		ai.PeriodAll,
+		ai.PeriodToday,
+		ai.PeriodThisWeek,
+		ai.PeriodThisMonth,
+	)
+
+	start := time.Now()
 	resultIntent, err := e.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(fmt.Sprintf(prompt, ai.IntentBrochureGeneration, ai.IntentUnknown)),
+			openai.SystemMessage(prompt),
 			openai.UserMessage(message),
 		},
 		Model: openai.ChatModelGPT4o,
 	})
+	observe(ctx, "intent", start, err)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("================================")
-	log.Printf("User Input: %s", message)
-	log.Printf("DetermineIntent: %s", resultIntent.Choices[0].Message.Content)
-	log.Printf("================================")
+	// The user input is not logged, it may contain personal data.
+	zerolog.Ctx(ctx).Debug().
+		Int("input_length", len(message)).
+		Str("result", resultIntent.Choices[0].Message.Content).
+		Msg("intent determined")
 
 	var resultIntentData ai.IntentResponse
 	err = json.Unmarshal([]byte(resultIntent.Choices[0].Message.Content), &resultIntentData)
@@ -90,14 +126,17 @@ func (e *OpenAIEngine) DetermineIntent(ctx context.Context, message string) (*ai
 	return &resultIntentData, nil
 }
 
-func (e *OpenAIEngine) GenerateBrochure(ctx context.Context, details ai.BrochureDetails) (string, error) {
+func (e *OpenAIEngine) GenerateBrochure(ctx context.Context, details ai.BrochureDetails) (*ai.GeneratedBrochure, error) {
 	var b strings.Builder
 	fmt.Fprintf(&b, "Design a clean, modern ecommerce brochure for brand “%s”. ", details.MerchantName)
-	fmt.Fprintf(&b, "Layout: square canvas, white or light background, soft shadows, neat grid. ")
+	fmt.Fprintf(&b, "Layout: %s canvas, white or light background, soft shadows, neat grid. ", brochureFormat(details.Format))
 	fmt.Fprintf(&b, "Top header: brand logo (from provided logo reference) at left, brand name at right in bold sans-serif. ")
 	fmt.Fprintf(&b, "Products: show each product photo as the hero within rounded cards. Under each photo, show the product name and a clear price tag. ")
 	fmt.Fprintf(&b, "Use consistent spacing, balanced margins, and visual hierarchy. If backgrounds are messy, neatly cut out products. ")
 	fmt.Fprintf(&b, "Typography: clean sans-serif; prices visually prominent; include subtle accents.\n\n")
+	if details.Style != "" {
+		fmt.Fprintf(&b, "Visual style: %s.\n\n", details.Style)
+	}
 
 	fmt.Fprintf(&b, "Products to include (name → price):\n")
 	for _, p := range details.Products {
@@ -111,61 +150,93 @@ func (e *OpenAIEngine) GenerateBrochure(ctx context.Context, details ai.Brochure
 	fmt.Fprintf(&b, "- Export PNG with transparent background where possible.\n")
 
 	prompt := b.String()
+	brochure := &ai.GeneratedBrochure{
+		Prompt: prompt,
+		Engine: "openai/" + string(openai.ImageModelGPTImage1),
+	}
 
+	start := time.Now()
 	res, err := e.client.Images.Generate(ctx, openai.ImageGenerateParams{
 		Model:  openai.ImageModelGPTImage1,
 		Prompt: prompt,
+		Size:   imageSize(details.Format),
 	})
+	observe(ctx, "image_generation", start, err)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if res.Data[0].URL != "" {
 		tmpDir := fmt.Sprintf("%s/openai", e.tempDir)
 		if err := os.MkdirAll(tmpDir, 0755); err != nil {
-			return "", err
+			return nil, err
 		}
 		out := filepath.Join(tmpDir, uuid.New().String()+".png")
 
-		// Use net/http to download the image
-		resp, err := http.Get(res.Data[0].URL)
+		// Use net/http to download the image, bound to ctx so shutdown can cancel it
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, res.Data[0].URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		defer resp.Body.Close()
 
 		f, err := os.Create(out)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		defer f.Close()
 
 		if _, err := io.Copy(f, resp.Body); err != nil {
-			return "", err
+			return nil, err
 		}
 
-		return out, nil
+		brochure.FilePath = out
+		return brochure, nil
 	}
 	if res.Data[0].B64JSON != "" {
 		data, err := base64.StdEncoding.DecodeString(res.Data[0].B64JSON)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		tmpDir := fmt.Sprintf("%s/openai", e.tempDir)
 		if err := os.MkdirAll(tmpDir, 0755); err != nil {
-			return "", err
+			return nil, err
 		}
 
 		out := filepath.Join(tmpDir, uuid.New().String()+".png")
 		if err := os.WriteFile(out, data, 0o644); err != nil {
-			return "", err
+			return nil, err
 		}
 
-		return out, nil
+		brochure.FilePath = out
+		return brochure, nil
 	}
 
-	return res.Data[0].URL, nil
+	brochure.FilePath = res.Data[0].URL
+	return brochure, nil
+}
+
+func brochureFormat(format ai.BrochureFormat) ai.BrochureFormat {
+	if format == "" {
+		return ai.BrochureFormatSquare
+	}
+	return format
+}
+
+func imageSize(format ai.BrochureFormat) openai.ImageGenerateParamsSize {
+	switch format {
+	case ai.BrochureFormatPortrait:
+		return openai.ImageGenerateParamsSize1024x1536
+	case ai.BrochureFormatLandscape:
+		return openai.ImageGenerateParamsSize1536x1024
+	default:
+		return openai.ImageGenerateParamsSize1024x1024
+	}
 }
 
 func (e *OpenAIEngine) MatchProducts(ctx context.Context, productNames []string, products []ai.Product) ([]ai.Product, error) {
@@ -207,21 +278,23 @@ func (e *OpenAIEngine) MatchProducts(ctx context.Context, productNames []string,
 	productNamesStr := strings.Join(productNames, ", ")
 
 	prompt = fmt.Sprintf(prompt, availableProductsStr, productNamesStr)
+	start := time.Now()
 	resultIntent, err := e.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.UserMessage(prompt),
 		},
 		Model: openai.ChatModelGPT4o,
 	})
+	observe(ctx, "product_matching", start, err)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("================================")
-	log.Printf("Available Product: %s", availableProductsStr)
-	log.Printf("Product Names: %s", productNamesStr)
-	log.Printf("Match Product Result: %s", resultIntent.Choices[0].Message.Content)
-	log.Printf("================================")
+	zerolog.Ctx(ctx).Debug().
+		Int("available_products", len(products)).
+		Strs("product_names", productNames).
+		Str("result", resultIntent.Choices[0].Message.Content).
+		Msg("products matched")
 
 	var productResult []ai.Product
 	err = json.Unmarshal([]byte(resultIntent.Choices[0].Message.Content), &productResult)
@@ -231,3 +304,14 @@ func (e *OpenAIEngine) MatchProducts(ctx context.Context, productNames []string,
 
 	return productResult, nil
 }
+
+// observe records the latency of an OpenAI request started at start.
+func observe(ctx context.Context, operation string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	metrics.OpenAIRequestDuration.WithLabelValues(operation, metrics.Result(err)).Observe(elapsed.Seconds())
+	zerolog.Ctx(ctx).Debug().
+		Err(err).
+		Str("operation", operation).
+		Dur("duration", elapsed).
+		Msg("openai request")
+}
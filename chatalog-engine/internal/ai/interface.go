@@ -8,11 +8,15 @@ import (
 type IntentResponse struct {
 	Intent   string   `json:"intent"`
 	Products []string `json:"products"`
+	// Period is set for brochure listing requests.
+	Period string `json:"period,omitempty"`
 }
 
 type Engine interface {
+	// Ping checks that the engine is reachable.
+	Ping(ctx context.Context) error
 	TranscribeAudio(ctx context.Context, file io.Reader) (string, error)
 	DetermineIntent(ctx context.Context, message string) (*IntentResponse, error)
-	GenerateBrochure(ctx context.Context, details BrochureDetails) (string, error)
+	GenerateBrochure(ctx context.Context, details BrochureDetails) (*GeneratedBrochure, error)
 	MatchProducts(ctx context.Context, productNames []string, products []Product) ([]Product, error)
 }
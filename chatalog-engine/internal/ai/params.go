@@ -5,7 +5,28 @@ type Product struct {
 	Price float64
 }
 
+type BrochureFormat string
+
+const (
+	BrochureFormatSquare    BrochureFormat = "square"
+	BrochureFormatPortrait  BrochureFormat = "portrait"
+	BrochureFormatLandscape BrochureFormat = "landscape"
+)
+
 type BrochureDetails struct {
 	MerchantName string
 	Products     []Product
+	// Style is a free-form description of the visual style, e.g. "minimalist".
+	Style string
+	// Format is the canvas orientation. Square is used when empty.
+	Format BrochureFormat
+}
+
+// GeneratedBrochure is a brochure image written by the engine and how it was
+// generated.
+type GeneratedBrochure struct {
+	FilePath string
+	Prompt   string
+	// Engine names the engine and model that generated the image.
+	Engine string
 }
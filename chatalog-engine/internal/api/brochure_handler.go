@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/job"
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/defryfazz/fazztalog/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+type brochureHandler struct {
+	merchantService merchant.Service
+	jobService      job.Service
+	worker          *job.Worker
+	storage         storage.Storage
+}
+
+type createBrochureRequest struct {
+	MerchantID   string                `json:"merchant_id" binding:"required"`
+	ProductNames []string              `json:"product_names" binding:"max=20,dive,required,max=200"`
+	Items        []brochureItemRequest `json:"items" binding:"max=20,dive"`
+	Style        string                `json:"style" binding:"max=200"`
+	Format       string                `json:"format" binding:"omitempty,oneof=square portrait landscape"`
+}
+
+type brochureItemRequest struct {
+	Name  string   `json:"name" binding:"required,max=200"`
+	Price *float64 `json:"price" binding:"required,gte=0"`
+}
+
+type brochureResponse struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	ImageURL  string    `json:"image_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// createBrochure queues a brochure generation and returns immediately. Clients
+// poll getBrochure until the status is completed or failed. Requests with the
+// same Idempotency-Key header return the same brochure.
+func (h *brochureHandler) createBrochure(c *gin.Context) {
+	var req createBrochureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	m, err := h.merchantService.GetMerchant(c.Request.Context(), req.MerchantID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = uuid.New().String()
+	}
+
+	options := job.Options{
+		Style:  req.Style,
+		Format: req.Format,
+	}
+	for _, item := range req.Items {
+		options.Items = append(options.Items, job.Item{Name: item.Name, Price: *item.Price})
+	}
+
+	brochureJob, created, err := h.jobService.CreateBrochureJob(c.Request.Context(), job.CreateBrochureJobParams{
+		IdempotencyKey: job.ChannelAPI + ":" + idempotencyKey,
+		MerchantPhone:  m.Phone,
+		Channel:        job.ChannelAPI,
+		ProductNames:   req.ProductNames,
+		Options:        options,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, toBrochureResponse(brochureJob))
+		return
+	}
+
+	if err := h.worker.Submit(*brochureJob); err != nil {
+		// A pending job would be returned to a retry with the same key
+		// without ever being queued, so it fails and a new key is needed.
+		if err := h.jobService.MarkFailed(context.WithoutCancel(c.Request.Context()), brochureJob.ID, err); err != nil {
+			zerolog.Ctx(c.Request.Context()).Error().Err(err).Str("job_id", brochureJob.ID).Msg("error marking brochure job as failed")
+		}
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, toBrochureResponse(brochureJob))
+}
+
+func (h *brochureHandler) getBrochure(c *gin.Context) {
+	brochureJob, err := h.getAPIJob(c.Request.Context(), c.Param("brochure_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toBrochureResponse(brochureJob))
+}
+
+func (h *brochureHandler) downloadBrochure(c *gin.Context) {
+	brochureJob, err := h.getAPIJob(c.Request.Context(), c.Param("brochure_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if brochureStatus(brochureJob.Status) != "completed" {
+		abortWithError(c, http.StatusConflict, "not_ready", "brochure is not generated yet")
+		return
+	}
+
+	image, obj, err := storage.Open(c.Request.Context(), h.storage, brochureJob.FilePath)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer image.Close()
+
+	c.DataFromReader(http.StatusOK, obj.Size, "image/png", image, map[string]string{
+		"Content-Disposition": `attachment; filename="` + brochureJob.ID + `.png"`,
+	})
+}
+
+// getAPIJob returns the job only if it was created through the API, so chat
+// brochures can not be read by guessing their IDs.
+func (h *brochureHandler) getAPIJob(ctx context.Context, id string) (*job.Job, error) {
+	brochureJob, err := h.jobService.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if brochureJob.Channel != job.ChannelAPI {
+		return nil, job.ErrJobNotFound
+	}
+	return brochureJob, nil
+}
+
+// NewBrochureWorker returns a worker that generates the brochures of jobs
+// created through the API.
+func NewBrochureWorker(merchantService merchant.Service, jobService job.Service, queueSize int, concurrency int) *job.Worker {
+	return job.NewWorker(queueSize, concurrency, func(ctx context.Context, brochureJob job.Job) {
+		items := make([]merchant.BrochureItem, 0, len(brochureJob.Options.Items))
+		for _, item := range brochureJob.Options.Items {
+			items = append(items, merchant.BrochureItem{Name: item.Name, Price: item.Price})
+		}
+
+		generated, err := merchantService.GenerateBrochure(ctx, merchant.BrochureRequest{
+			MerchantPhone: brochureJob.MerchantPhone,
+			JobID:         brochureJob.ID,
+			ProductNames:  brochureJob.ProductNames,
+			Items:         items,
+			Style:         brochureJob.Options.Style,
+			Format:        brochureJob.Options.Format,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Msg("brochure job interrupted, leaving it for resume")
+				return
+			}
+			zerolog.Ctx(ctx).Error().Err(err).Msg("error generating brochure")
+			if err := jobService.MarkFailed(context.WithoutCancel(ctx), brochureJob.ID, err); err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Msg("error marking brochure job as failed")
+			}
+			return
+		}
+
+		// The brochure is stored, its status must be written even when
+		// shutdown cancels ctx now.
+		if err := jobService.MarkGenerated(context.WithoutCancel(ctx), brochureJob.ID, generated.FilePath); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("error marking brochure job as generated")
+		}
+	})
+}
+
+// ResumeBrochureJobs queues the API jobs that were left pending by a previous
+// run.
+func ResumeBrochureJobs(ctx context.Context, jobService job.Service, worker *job.Worker) error {
+	jobs, err := jobService.GetUnfinishedJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, brochureJob := range jobs {
+		if brochureJob.Channel != job.ChannelAPI || brochureJob.Status != job.StatusPending {
+			continue
+		}
+		if err := worker.Submit(brochureJob); err != nil {
+			if errors.Is(err, job.ErrQueueFull) {
+				zerolog.Ctx(ctx).Warn().Str("job_id", brochureJob.ID).Msg("brochure queue is full, job stays pending until the next start")
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// brochureStatus maps job statuses to the statuses exposed by the API. API
+// jobs are done once the brochure is generated since there is no chat to send
+// it to.
+func brochureStatus(status job.Status) string {
+	switch status {
+	case job.StatusGenerated, job.StatusSent:
+		return "completed"
+	case job.StatusFailed:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+func toBrochureResponse(j *job.Job) brochureResponse {
+	res := brochureResponse{
+		ID:        j.ID,
+		Status:    brochureStatus(j.Status),
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+	}
+	switch res.Status {
+	case "completed":
+		res.ImageURL = "/v1/brochures/" + j.ID + "/image"
+	case "failed":
+		// The job error may contain upstream details, it is only logged.
+		res.Error = "brochure generation failed"
+	}
+	return res
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/device"
+	"github.com/gin-gonic/gin"
+)
+
+type deviceHandler struct {
+	deviceService device.Service
+}
+
+type devicePhoneURI struct {
+	Phone string `uri:"phone" binding:"required,numeric,min=8,max=15"`
+}
+
+type deviceRoutingRequest struct {
+	Region      string   `json:"region" binding:"max=50"`
+	MerchantIDs []string `json:"merchant_ids" binding:"max=1000,dive,required,max=64"`
+}
+
+type deviceResponse struct {
+	Phone       string    `json:"phone"`
+	Region      string    `json:"region"`
+	MerchantIDs []string  `json:"merchant_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (h *deviceHandler) listDevices(c *gin.Context) {
+	devices, err := h.deviceService.ListDevices(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	data := make([]deviceResponse, 0, len(devices))
+	for _, d := range devices {
+		data = append(data, toDeviceResponse(d))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *deviceHandler) getDevice(c *gin.Context) {
+	d, err := h.deviceService.GetDevice(c.Request.Context(), c.Param("phone"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toDeviceResponse(*d))
+}
+
+// setDeviceRouting sets the merchants and region a WhatsApp number serves.
+// The number does not need to be paired yet.
+func (h *deviceHandler) setDeviceRouting(c *gin.Context) {
+	var uri devicePhoneURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	var req deviceRoutingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	d, err := h.deviceService.SetRouting(c.Request.Context(), uri.Phone, device.RoutingParams{
+		Region:      req.Region,
+		MerchantIDs: req.MerchantIDs,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toDeviceResponse(*d))
+}
+
+func (h *deviceHandler) deleteDevice(c *gin.Context) {
+	if err := h.deviceService.DeleteDevice(c.Request.Context(), c.Param("phone")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toDeviceResponse(d device.Device) deviceResponse {
+	return deviceResponse{
+		Phone:       d.Phone,
+		Region:      d.Region,
+		MerchantIDs: d.MerchantIDs,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+	}
+}
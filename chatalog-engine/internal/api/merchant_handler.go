@@ -0,0 +1,507 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxImportProducts = 1000
+	maxImageSize      = 5 << 20
+)
+
+type merchantHandler struct {
+	merchantService merchant.Service
+}
+
+type merchantRequest struct {
+	Name   string `json:"name" binding:"required,max=100"`
+	Phone  string `json:"phone" binding:"required,numeric,min=8,max=15"`
+	Region string `json:"region" binding:"max=50"`
+	// SheetURL is the CSV export URL of the merchant's own sheet.
+	SheetURL string `json:"sheet_url" binding:"omitempty,url,max=500"`
+}
+
+type merchantResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	Region   string `json:"region"`
+	LogoURL  string `json:"logo_url,omitempty"`
+	SheetURL string `json:"sheet_url,omitempty"`
+}
+
+// accountRequest links a user of a channel without phone numbers to the
+// merchant, e.g. the numeric user ID of a Telegram user.
+type accountRequest struct {
+	Channel    string `json:"channel" binding:"required,oneof=telegram"`
+	ExternalID string `json:"external_id" binding:"required,numeric,max=20"`
+}
+
+type accountResponse struct {
+	Channel    string `json:"channel"`
+	ExternalID string `json:"external_id"`
+	MerchantID string `json:"merchant_id"`
+}
+
+type productRequest struct {
+	ID    string   `json:"id" binding:"omitempty,max=64"`
+	Name  string   `json:"name" binding:"required,max=200"`
+	Price *float64 `json:"price" binding:"required,gte=0"`
+}
+
+type importProductsRequest struct {
+	Products []productRequest `json:"products" binding:"required,min=1,max=1000,dive"`
+}
+
+type productResponse struct {
+	ID         string  `json:"id"`
+	MerchantID string  `json:"merchant_id"`
+	Name       string  `json:"name"`
+	Price      float64 `json:"price"`
+	PhotoURL   string  `json:"photo_url,omitempty"`
+}
+
+type importProductsResponse struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+func (h *merchantHandler) listMerchants(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	merchants, total, err := h.merchantService.ListMerchants(c.Request.Context(), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	data := make([]merchantResponse, 0, len(merchants))
+	for _, m := range merchants {
+		data = append(data, toMerchantResponse(m))
+	}
+	c.JSON(http.StatusOK, listResponse[merchantResponse]{
+		Data:       data,
+		Pagination: pagination{Limit: page.Limit, Offset: page.Offset, Total: total},
+	})
+}
+
+func (h *merchantHandler) getMerchant(c *gin.Context) {
+	m, err := h.merchantService.GetMerchant(c.Request.Context(), c.Param("merchant_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toMerchantResponse(*m))
+}
+
+func (h *merchantHandler) createMerchant(c *gin.Context) {
+	var req merchantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	m, err := h.merchantService.CreateMerchant(c.Request.Context(), merchant.MerchantParams{
+		Name:     req.Name,
+		Phone:    req.Phone,
+		Region:   req.Region,
+		SheetURL: req.SheetURL,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toMerchantResponse(*m))
+}
+
+func (h *merchantHandler) updateMerchant(c *gin.Context) {
+	var req merchantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	m, err := h.merchantService.UpdateMerchant(c.Request.Context(), c.Param("merchant_id"), merchant.MerchantParams{
+		Name:     req.Name,
+		Phone:    req.Phone,
+		Region:   req.Region,
+		SheetURL: req.SheetURL,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toMerchantResponse(*m))
+}
+
+func (h *merchantHandler) deleteMerchant(c *gin.Context) {
+	if err := h.merchantService.DeleteMerchant(c.Request.Context(), c.Param("merchant_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// uploadLogo replaces the merchant logo with the image in the request body.
+func (h *merchantHandler) uploadLogo(c *gin.Context) {
+	m, err := h.merchantService.SetMerchantLogo(c.Request.Context(), c.Param("merchant_id"), requestImage(c))
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toMerchantResponse(*m))
+}
+
+func (h *merchantHandler) downloadLogo(c *gin.Context) {
+	image, obj, err := h.merchantService.OpenMerchantLogo(c.Request.Context(), c.Param("merchant_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer image.Close()
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, image, nil)
+}
+
+func (h *merchantHandler) listAccounts(c *gin.Context) {
+	accounts, err := h.merchantService.ListAccounts(c.Request.Context(), c.Param("merchant_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	data := make([]accountResponse, 0, len(accounts))
+	for _, a := range accounts {
+		data = append(data, toAccountResponse(a))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// linkAccount lets the channel user talk to the bot as the merchant.
+func (h *merchantHandler) linkAccount(c *gin.Context) {
+	var req accountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	a, err := h.merchantService.LinkAccount(c.Request.Context(), c.Param("merchant_id"), req.Channel, req.ExternalID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toAccountResponse(*a))
+}
+
+func (h *merchantHandler) unlinkAccount(c *gin.Context) {
+	err := h.merchantService.UnlinkAccount(c.Request.Context(), c.Param("merchant_id"), c.Param("channel"), c.Param("external_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *merchantHandler) listProducts(c *gin.Context) {
+	page, err := parsePage(c)
+	if err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	products, total, err := h.merchantService.ListProducts(c.Request.Context(), c.Param("merchant_id"), page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	data := make([]productResponse, 0, len(products))
+	for _, p := range products {
+		data = append(data, toProductResponse(p))
+	}
+	c.JSON(http.StatusOK, listResponse[productResponse]{
+		Data:       data,
+		Pagination: pagination{Limit: page.Limit, Offset: page.Offset, Total: total},
+	})
+}
+
+func (h *merchantHandler) getProduct(c *gin.Context) {
+	p, err := h.merchantService.GetProduct(c.Request.Context(), c.Param("merchant_id"), c.Param("product_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toProductResponse(*p))
+}
+
+func (h *merchantHandler) createProduct(c *gin.Context) {
+	var req productRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	p, err := h.merchantService.CreateProduct(c.Request.Context(), c.Param("merchant_id"), req.toParams())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toProductResponse(*p))
+}
+
+func (h *merchantHandler) updateProduct(c *gin.Context) {
+	var req productRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	p, err := h.merchantService.UpdateProduct(c.Request.Context(), c.Param("merchant_id"), c.Param("product_id"), req.toParams())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toProductResponse(*p))
+}
+
+func (h *merchantHandler) deleteProduct(c *gin.Context) {
+	if err := h.merchantService.DeleteProduct(c.Request.Context(), c.Param("merchant_id"), c.Param("product_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// uploadProductPhoto replaces the product photo with the image in the request
+// body.
+func (h *merchantHandler) uploadProductPhoto(c *gin.Context) {
+	p, err := h.merchantService.SetProductPhoto(c.Request.Context(), c.Param("merchant_id"), c.Param("product_id"), requestImage(c))
+	if err != nil {
+		respondImageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toProductResponse(*p))
+}
+
+func (h *merchantHandler) downloadProductPhoto(c *gin.Context) {
+	image, obj, err := h.merchantService.OpenProductPhoto(c.Request.Context(), c.Param("merchant_id"), c.Param("product_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer image.Close()
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, image, nil)
+}
+
+// requestImage reads the image from the raw request body, limited to
+// maxImageSize.
+func requestImage(c *gin.Context) merchant.Image {
+	size := c.Request.ContentLength
+	if size > maxImageSize {
+		size = -1
+	}
+	return merchant.Image{
+		Content:     http.MaxBytesReader(c.Writer, c.Request.Body, maxImageSize),
+		Size:        size,
+		ContentType: c.ContentType(),
+	}
+}
+
+func respondImageError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		abortWithError(c, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("image must not be larger than %d bytes", maxImageSize))
+		return
+	}
+	respondError(c, err)
+}
+
+// importProducts accepts either a JSON body {"products": [...]} or a CSV body
+// with a header row containing name and price, and optionally id.
+func (h *merchantHandler) importProducts(c *gin.Context) {
+	var (
+		params []merchant.ProductParams
+		err    error
+	)
+	if c.ContentType() == "text/csv" {
+		params, err = parseProductsCSV(c.Request.Body)
+	} else {
+		var req importProductsRequest
+		if err = c.ShouldBindJSON(&req); err == nil {
+			for _, p := range req.Products {
+				params = append(params, p.toParams())
+			}
+		}
+	}
+	if err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	res, err := h.merchantService.ImportProducts(c.Request.Context(), c.Param("merchant_id"), params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, importProductsResponse{
+		Created: res.Created,
+		Updated: res.Updated,
+	})
+}
+
+func parseProductsCSV(r io.Reader) ([]merchant.ProductParams, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, errors.New("csv must have a header row and at least one product")
+	}
+	if len(rows)-1 > maxImportProducts {
+		return nil, fmt.Errorf("csv must not have more than %d products", maxImportProducts)
+	}
+
+	columns := map[string]int{}
+	for i, h := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	nameI, hasName := columns["name"]
+	priceI, hasPrice := columns["price"]
+	idI, hasID := columns["id"]
+	if !hasName || !hasPrice {
+		return nil, errors.New("csv header must contain name and price")
+	}
+
+	params := make([]merchant.ProductParams, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		line := i + 2
+		get := func(idx int) string {
+			if idx < len(row) {
+				return strings.TrimSpace(row[idx])
+			}
+			return ""
+		}
+
+		name := get(nameI)
+		if name == "" {
+			return nil, fmt.Errorf("line %d: name is required", line)
+		}
+		price, err := parsePrice(get(priceI))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		p := merchant.ProductParams{Name: name, Price: price}
+		if hasID {
+			p.ID = get(idI)
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+var thousandsPattern = regexp.MustCompile(`^\d{1,3}([.,]\d{3})+$`)
+
+// parsePrice reads prices as merchants write them: "15000", "15.000",
+// "Rp 15.000,00", "15,000.50", "20rb", "1,5jt". A single separator followed
+// by three digits groups thousands, as in Indonesian prices.
+func parsePrice(s string) (float64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	for _, prefix := range []string{"rp.", "rp", "idr"} {
+		if strings.HasPrefix(v, prefix) {
+			v = strings.TrimSpace(v[len(prefix):])
+			break
+		}
+	}
+	multiplier := 1.0
+	for _, suffix := range []struct {
+		text string
+		mul  float64
+	}{{"ribu", 1e3}, {"rb", 1e3}, {"k", 1e3}, {"juta", 1e6}, {"jt", 1e6}} {
+		if strings.HasSuffix(v, suffix.text) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, suffix.text))
+			multiplier = suffix.mul
+			break
+		}
+	}
+	v = strings.ReplaceAll(v, " ", "")
+
+	dot, comma := strings.LastIndex(v, "."), strings.LastIndex(v, ",")
+	switch {
+	case dot >= 0 && comma >= 0:
+		// The last separator is the decimal one.
+		if dot > comma {
+			v = strings.ReplaceAll(v, ",", "")
+		} else {
+			v = strings.ReplaceAll(strings.ReplaceAll(v, ".", ""), ",", ".")
+		}
+	case thousandsPattern.MatchString(v):
+		v = strings.NewReplacer(".", "", ",", "").Replace(v)
+	case comma >= 0:
+		v = strings.ReplaceAll(v, ",", ".")
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	if err != nil || price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return price * multiplier, nil
+}
+
+func (r productRequest) toParams() merchant.ProductParams {
+	return merchant.ProductParams{
+		ID:    r.ID,
+		Name:  r.Name,
+		Price: *r.Price,
+	}
+}
+
+func toMerchantResponse(m merchant.Merchant) merchantResponse {
+	res := merchantResponse{
+		ID:       m.ID,
+		Name:     m.Name,
+		Phone:    m.Phone,
+		Region:   m.Region,
+		SheetURL: m.SheetURL,
+	}
+	if m.LogoKey != "" {
+		res.LogoURL = "/v1/merchants/" + m.ID + "/logo"
+	}
+	return res
+}
+
+func toAccountResponse(a merchant.Account) accountResponse {
+	return accountResponse{
+		Channel:    a.Channel,
+		ExternalID: a.ExternalID,
+		MerchantID: a.MerchantID,
+	}
+}
+
+func toProductResponse(p merchant.Product) productResponse {
+	res := productResponse{
+		ID:         p.ID,
+		MerchantID: p.MerchantID,
+		Name:       p.Name,
+		Price:      p.Price,
+	}
+	if p.PhotoKey != "" {
+		res.PhotoURL = "/v1/merchants/" + p.MerchantID + "/products/" + p.ID + "/photo"
+	}
+	return res
+}
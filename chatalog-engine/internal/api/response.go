@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/defryfazz/fazztalog/internal/device"
+	"github.com/defryfazz/fazztalog/internal/job"
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/defryfazz/fazztalog/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Fields  []fieldDetail `json:"fields,omitempty"`
+}
+
+type fieldDetail struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+}
+
+type listResponse[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination pagination `json:"pagination"`
+}
+
+type pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+func abortWithError(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, errorBody{
+		Error: errorDetail{Code: code, Message: message},
+	})
+}
+
+// respondError maps domain errors to HTTP errors. Unknown errors are logged
+// and reported as internal errors without leaking details.
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, merchant.ErrMerchantNotFound), errors.Is(err, merchant.ErrProductNotFound), errors.Is(err, job.ErrJobNotFound), errors.Is(err, device.ErrDeviceNotFound), errors.Is(err, merchant.ErrImageNotFound), errors.Is(err, storage.ErrObjectNotFound), errors.Is(err, merchant.ErrAccountNotFound):
+		abortWithError(c, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, merchant.ErrUnsupportedImage):
+		abortWithError(c, http.StatusUnsupportedMediaType, "unsupported_media_type", err.Error())
+	case errors.Is(err, merchant.ErrPhoneTaken), errors.Is(err, merchant.ErrProductExists), errors.Is(err, merchant.ErrAccountTaken):
+		abortWithError(c, http.StatusConflict, "conflict", err.Error())
+	case errors.Is(err, job.ErrQueueFull), errors.Is(err, job.ErrWorkerStopped):
+		abortWithError(c, http.StatusServiceUnavailable, "unavailable", err.Error())
+	default:
+		zerolog.Ctx(c.Request.Context()).Error().Err(err).Msg("error handling request")
+		abortWithError(c, http.StatusInternalServerError, "internal_error", "internal server error")
+	}
+}
+
+func respondValidationError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		abortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	fields := make([]fieldDetail, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, fieldDetail{
+			Field: strings.ToLower(fe.Field()),
+			Rule:  fe.Tag(),
+		})
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, errorBody{
+		Error: errorDetail{
+			Code:    "invalid_request",
+			Message: "request validation failed",
+			Fields:  fields,
+		},
+	})
+}
+
+// parsePage reads the limit and offset query parameters.
+func parsePage(c *gin.Context) (merchant.Page, error) {
+	page := merchant.Page{Limit: defaultLimit}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return page, errors.New("limit must be a positive integer")
+		}
+		page.Limit = min(limit, maxLimit)
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return page, errors.New("offset must be a non-negative integer")
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
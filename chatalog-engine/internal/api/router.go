@@ -0,0 +1,137 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/device"
+	"github.com/defryfazz/fazztalog/internal/health"
+	"github.com/defryfazz/fazztalog/internal/job"
+	"github.com/defryfazz/fazztalog/internal/logger"
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/defryfazz/fazztalog/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+type RouterParams struct {
+	MerchantService merchant.Service
+	JobService      job.Service
+	DeviceService   device.Service
+	BrochureWorker  *job.Worker
+	Storage         storage.Storage
+	Health          *health.Checker
+	AdminToken      string
+	// APIToken authorizes the web frontend and partner systems to generate
+	// brochures. It does not give access to the admin endpoints.
+	APIToken string
+}
+
+func NewRouter(params RouterParams) *gin.Engine {
+	router := gin.New()
+	// Probes and scrapes would drown the request log.
+	router.Use(requestLogger("/healthz", "/readyz", "/metrics"), gin.Recovery())
+	router.NoRoute(func(c *gin.Context) {
+		abortWithError(c, http.StatusNotFound, "not_found", "route not found")
+	})
+
+	merchants := &merchantHandler{merchantService: params.MerchantService}
+	devices := &deviceHandler{deviceService: params.DeviceService}
+	brochures := &brochureHandler{
+		merchantService: params.MerchantService,
+		jobService:      params.JobService,
+		worker:          params.BrochureWorker,
+		storage:         params.Storage,
+	}
+
+	router.GET("/healthz", gin.WrapF(params.Health.ServeHealthz))
+	router.GET("/readyz", gin.WrapF(params.Health.ServeReadyz))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	public := router.Group("/v1", requireToken(params.AdminToken, params.APIToken))
+	public.POST("/brochures", brochures.createBrochure)
+	public.GET("/brochures/:brochure_id", brochures.getBrochure)
+	public.GET("/brochures/:brochure_id/image", brochures.downloadBrochure)
+
+	admin := router.Group("/v1", requireToken(params.AdminToken))
+	admin.GET("/merchants", merchants.listMerchants)
+	admin.POST("/merchants", merchants.createMerchant)
+	admin.GET("/merchants/:merchant_id", merchants.getMerchant)
+	admin.PUT("/merchants/:merchant_id", merchants.updateMerchant)
+	admin.DELETE("/merchants/:merchant_id", merchants.deleteMerchant)
+	admin.GET("/merchants/:merchant_id/logo", merchants.downloadLogo)
+	admin.PUT("/merchants/:merchant_id/logo", merchants.uploadLogo)
+	admin.GET("/merchants/:merchant_id/accounts", merchants.listAccounts)
+	admin.POST("/merchants/:merchant_id/accounts", merchants.linkAccount)
+	admin.DELETE("/merchants/:merchant_id/accounts/:channel/:external_id", merchants.unlinkAccount)
+	admin.GET("/merchants/:merchant_id/products", merchants.listProducts)
+	admin.POST("/merchants/:merchant_id/products", merchants.createProduct)
+	admin.POST("/merchants/:merchant_id/products/import", merchants.importProducts)
+	admin.GET("/merchants/:merchant_id/products/:product_id", merchants.getProduct)
+	admin.PUT("/merchants/:merchant_id/products/:product_id", merchants.updateProduct)
+	admin.DELETE("/merchants/:merchant_id/products/:product_id", merchants.deleteProduct)
+	admin.GET("/merchants/:merchant_id/products/:product_id/photo", merchants.downloadProductPhoto)
+	admin.PUT("/merchants/:merchant_id/products/:product_id/photo", merchants.uploadProductPhoto)
+	admin.GET("/devices", devices.listDevices)
+	admin.GET("/devices/:phone", devices.getDevice)
+	admin.PUT("/devices/:phone", devices.setDeviceRouting)
+	admin.DELETE("/devices/:phone", devices.deleteDevice)
+
+	return router
+}
+
+// requestLogger tags the request context with a correlation ID, taken from
+// the X-Request-ID header when the client sent a usable one, and logs every
+// request except the ones to skipPaths.
+func requestLogger(skipPaths ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		ctx := logger.WithCorrelationID(c.Request.Context(), requestID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(requestIDHeader, requestID)
+
+		start := time.Now()
+		c.Next()
+
+		if slices.Contains(skipPaths, c.Request.URL.Path) {
+			return
+		}
+		zerolog.Ctx(ctx).Info().
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", c.Writer.Status()).
+			Dur("duration", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Msg("request handled")
+	}
+}
+
+// requireToken rejects requests that do not carry one of the tokens as a
+// bearer token. Empty tokens never match.
+func requireToken(tokens ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok && got != "" {
+			for _, token := range tokens {
+				if token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+					c.Next()
+					return
+				}
+			}
+		}
+		abortWithError(c, http.StatusUnauthorized, "unauthorized", "missing or invalid token")
+	}
+}
@@ -2,10 +2,19 @@ package app
 
 import (
 	"database/sql"
+	"strings"
 
+	"github.com/defryfazz/fazztalog/config"
 	"github.com/defryfazz/fazztalog/internal/ai"
 	"github.com/defryfazz/fazztalog/internal/ai/engine"
+	"github.com/defryfazz/fazztalog/internal/brochure"
+	"github.com/defryfazz/fazztalog/internal/conversation"
+	"github.com/defryfazz/fazztalog/internal/device"
+	"github.com/defryfazz/fazztalog/internal/job"
 	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/defryfazz/fazztalog/internal/message"
+	"github.com/defryfazz/fazztalog/internal/sheet"
+	"github.com/defryfazz/fazztalog/internal/storage"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 )
@@ -13,25 +22,91 @@ import (
 type AppContainer struct {
 	AIEngine        ai.Engine
 	MerchantService merchant.Service
+	MessageService  message.Service
+	JobService      job.Service
+	DeviceService   device.Service
+	BrochureService brochure.Service
+	SheetService    sheet.Service
+	Storage         storage.Storage
+	Conversation    *conversation.Handler
 }
 
 type SetupAppParams struct {
-	DB            *sql.DB
-	OpenAIToken   string
-	TempDirectory string
+	DB      *sql.DB
+	Config  *config.Config
+	Storage storage.Storage
 }
 
 func SetupApp(params SetupAppParams) AppContainer {
 	repositories := setupRepositories(params.DB)
 
 	client := openai.NewClient(
-		option.WithAPIKey(params.OpenAIToken),
+		option.WithAPIKey(params.Config.OpenAIToken),
 	)
-	aiEngine := engine.NewOpenAIEngine(client, params.TempDirectory)
-	merchantService := merchant.NewService(repositories.Merchant, aiEngine)
+	aiEngine := engine.NewOpenAIEngine(client, params.Config.TempFolderPath)
+	brochureService := brochure.NewService(repositories.Brochure)
+	merchantService := merchant.NewService(merchant.ServiceParams{
+		Repository:      repositories.Merchant,
+		AIEngine:        aiEngine,
+		BrochureService: brochureService,
+		Storage:         params.Storage,
+	})
+	messageService := message.NewService(repositories.Message)
+	jobService := job.NewService(repositories.Job)
+	deviceService := device.NewService(repositories.Device, merchantService)
+	sheetService := sheet.NewService(sheet.ServiceParams{
+		Source:          newSheetSource(params.Config),
+		SharedURL:       params.Config.Sheet.URL,
+		MerchantService: merchantService,
+	})
+	conversationHandler := conversation.NewHandler(conversation.HandlerParams{
+		AIEngine:        aiEngine,
+		MerchantService: merchantService,
+		MessageService:  messageService,
+		JobService:      jobService,
+		DeviceService:   deviceService,
+		BrochureService: brochureService,
+		SheetService:    sheetService,
+		Storage:         params.Storage,
+		TempDirectory:   params.Config.TempFolderPath,
+	})
 
 	return AppContainer{
 		AIEngine:        aiEngine,
 		MerchantService: merchantService,
+		MessageService:  messageService,
+		JobService:      jobService,
+		DeviceService:   deviceService,
+		BrochureService: brochureService,
+		SheetService:    sheetService,
+		Storage:         params.Storage,
+		Conversation:    conversationHandler,
+	}
+}
+
+// newSheetSource returns the source of the sheet sync, or nil when no sheet
+// server is configured.
+func newSheetSource(cfg *config.Config) sheet.Source {
+	command := strings.Fields(cfg.Sheet.ServerCommand)
+	if len(command) == 0 {
+		return nil
+	}
+	return sheet.NewMCPSource(sheet.MCPSourceParams{
+		Command: command,
+		Timeout: cfg.Sheet.Timeout,
+	})
+}
+
+// NewSheetScheduler returns the scheduler of the sheet sync. It does not run
+// when no sheet server or interval is configured.
+func NewSheetScheduler(cfg *config.Config, appContainer AppContainer) *sheet.Scheduler {
+	interval := cfg.Sheet.SyncInterval
+	if cfg.Sheet.ServerCommand == "" {
+		interval = 0
 	}
+	return sheet.NewScheduler(sheet.SchedulerParams{
+		Service:  appContainer.SheetService,
+		Notifier: appContainer.Conversation,
+		Interval: interval,
+	})
 }
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/defryfazz/fazztalog/config"
+	"github.com/defryfazz/fazztalog/internal/logger"
+	"github.com/rs/zerolog/log"
+)
+
+// LoadConfig loads the configuration of a command from the file given by the
+// -config flag or CONFIG_FILE and the environment, and sets up logging with it.
+// It exits with every configuration problem listed when the settings the
+// command needs are missing or invalid.
+func LoadConfig(sections ...config.Section) *config.Config {
+	path := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*path, sections...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logger.Setup(logger.SetupParams{
+		Level:   cfg.Log.Level,
+		Format:  cfg.Log.Format,
+		Secrets: cfg.Secrets(),
+	})
+
+	event := log.Info().Str("config_file", *path)
+	for key, value := range cfg.Summary() {
+		event = event.Str(key, value)
+	}
+	event.Msg("configuration loaded")
+	return cfg
+}
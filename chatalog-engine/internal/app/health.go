@@ -0,0 +1,25 @@
+package app
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/ai"
+	"github.com/defryfazz/fazztalog/internal/health"
+	"github.com/defryfazz/fazztalog/internal/storage"
+)
+
+// engineCheckInterval limits how often readiness probes call the AI engine.
+const engineCheckInterval = time.Minute
+
+// NewHealthChecker returns a checker with the readiness checks shared by every
+// process. Processes add the checks of their channel.
+func NewHealthChecker(db *sql.DB, aiEngine ai.Engine, mediaStorage storage.Storage, timeout time.Duration) *health.Checker {
+	checker := health.NewChecker(timeout)
+	checker.Add("database", db.PingContext)
+	checker.Add("ai_engine", health.Cached(engineCheckInterval, aiEngine.Ping))
+	if pinger, ok := mediaStorage.(storage.Pinger); ok {
+		checker.Add("storage", pinger.Ping)
+	}
+	return checker
+}
@@ -3,18 +3,38 @@ package app
 import (
 	"database/sql"
 
+	"github.com/defryfazz/fazztalog/internal/brochure"
+	brochurerepo "github.com/defryfazz/fazztalog/internal/brochure/repository"
+	"github.com/defryfazz/fazztalog/internal/device"
+	devicerepo "github.com/defryfazz/fazztalog/internal/device/repository"
+	"github.com/defryfazz/fazztalog/internal/job"
+	jobrepo "github.com/defryfazz/fazztalog/internal/job/repository"
 	"github.com/defryfazz/fazztalog/internal/merchant"
 	merchantrepo "github.com/defryfazz/fazztalog/internal/merchant/repository"
+	"github.com/defryfazz/fazztalog/internal/message"
+	messagerepo "github.com/defryfazz/fazztalog/internal/message/repository"
 )
 
 type repository struct {
 	Merchant merchant.Repository
+	Message  message.Repository
+	Job      job.Repository
+	Device   device.Repository
+	Brochure brochure.Repository
 }
 
 func setupRepositories(db *sql.DB) repository {
 	merchantRepo := merchantrepo.NewMerchantRepository(db)
+	messageRepo := messagerepo.NewMessageRepository(db)
+	jobRepo := jobrepo.NewJobRepository(db)
+	deviceRepo := devicerepo.NewDeviceRepository(db)
+	brochureRepo := brochurerepo.NewBrochureRepository(db)
 
 	return repository{
 		Merchant: merchantRepo,
+		Message:  messageRepo,
+		Job:      jobRepo,
+		Device:   deviceRepo,
+		Brochure: brochureRepo,
 	}
 }
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/defryfazz/fazztalog/config"
+	"github.com/defryfazz/fazztalog/internal/storage"
+)
+
+// SetupStorage returns the storage of brochures, product photos and logos
+// selected by the configuration.
+func SetupStorage(ctx context.Context, cfg *config.Config) (storage.Storage, error) {
+	if cfg.Storage.Backend == config.StorageBackendS3 {
+		return storage.NewS3(ctx, storage.S3Params{
+			Endpoint:  cfg.Storage.S3Endpoint,
+			Region:    cfg.Storage.S3Region,
+			Bucket:    cfg.Storage.S3Bucket,
+			AccessKey: cfg.Storage.S3AccessKey,
+			SecretKey: cfg.Storage.S3SecretKey,
+			UseSSL:    cfg.Storage.S3UseSSL,
+		})
+	}
+	return storage.NewLocal(localStoragePath(cfg))
+}
+
+// tempSubdirectories are the directories of the temp folder the app writes
+// temp files to: generated images, voice notes being transcribed and media
+// downloaded from storage.
+var tempSubdirectories = []string{"openai", "transcriptions", "downloads"}
+
+// NewJanitor returns the janitor that removes stale temp files and expired
+// brochures. Only the app's own temp directories are cleaned, and the local
+// storage and the databases never are, even when they live inside them.
+func NewJanitor(cfg *config.Config, mediaStorage storage.Storage) *storage.Janitor {
+	var exclude []string
+	if cfg.Storage.Backend == config.StorageBackendLocal {
+		exclude = append(exclude, localStoragePath(cfg))
+	}
+	for _, db := range []string{cfg.SQLitePath, cfg.WhatsApp.SQLPath} {
+		if db != "" {
+			exclude = append(exclude, db)
+		}
+	}
+	return storage.NewJanitor(storage.JanitorParams{
+		Storage:            mediaStorage,
+		TempDirectory:      cfg.TempFolderPath,
+		TempSubdirectories: tempSubdirectories,
+		Exclude:            exclude,
+		TempRetention:      cfg.Storage.TempRetention,
+		BrochureRetention:  cfg.Storage.BrochureRetention,
+		Interval:           cfg.Storage.JanitorInterval,
+	})
+}
+
+func localStoragePath(cfg *config.Config) string {
+	if cfg.Storage.LocalPath != "" {
+		return cfg.Storage.LocalPath
+	}
+	return filepath.Join(cfg.TempFolderPath, "media")
+}
@@ -0,0 +1,5 @@
+package brochure
+
+import "errors"
+
+var ErrBrochureNotFound = errors.New("brochure not found")
@@ -0,0 +1,21 @@
+package brochure
+
+import (
+	"context"
+	"time"
+)
+
+type Service interface {
+	CreateBrochure(ctx context.Context, params CreateBrochureParams) (*Brochure, error)
+	// GetLatestBrochure returns the most recent brochure of the merchant.
+	GetLatestBrochure(ctx context.Context, merchantID string) (*Brochure, error)
+	// ListBrochures returns up to limit brochures of the merchant created since
+	// the given time, newest first.
+	ListBrochures(ctx context.Context, merchantID string, since time.Time, limit int) ([]Brochure, error)
+}
+
+type Repository interface {
+	CreateBrochure(ctx context.Context, b Brochure) error
+	GetLatestBrochure(ctx context.Context, merchantID string) (*Brochure, error)
+	ListBrochures(ctx context.Context, merchantID string, since time.Time, limit int) ([]Brochure, error)
+}
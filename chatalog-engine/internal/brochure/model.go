@@ -0,0 +1,37 @@
+package brochure
+
+import "time"
+
+// Brochure is a generated brochure and everything it was generated from, so it
+// can be sent again or listed later.
+type Brochure struct {
+	ID         string
+	MerchantID string
+	// JobID is the brochure job the brochure was generated for, if any.
+	JobID    string
+	Products []Product
+	Style    string
+	Format   string
+	Prompt   string
+	Engine   string
+	FilePath string
+
+	CreatedAt time.Time
+}
+
+// Product is a product as it was priced on the brochure.
+type Product struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type CreateBrochureParams struct {
+	MerchantID string
+	JobID      string
+	Products   []Product
+	Style      string
+	Format     string
+	Prompt     string
+	Engine     string
+	FilePath   string
+}
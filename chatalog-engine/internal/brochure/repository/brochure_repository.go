@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/brochure"
+)
+
+type BrochureRepository struct {
+	db *sql.DB
+}
+
+func NewBrochureRepository(db *sql.DB) *BrochureRepository {
+	return &BrochureRepository{
+		db: db,
+	}
+}
+
+func (r *BrochureRepository) CreateBrochure(ctx context.Context, b brochure.Brochure) error {
+	products, err := json.Marshal(b.Products)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO brochures (id, merchant_id, job_id, products, style, format, prompt, engine, file_path, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		b.ID,
+		b.MerchantID,
+		b.JobID,
+		string(products),
+		b.Style,
+		b.Format,
+		b.Prompt,
+		b.Engine,
+		b.FilePath,
+		b.CreatedAt,
+	)
+	return err
+}
+
+func (r *BrochureRepository) GetLatestBrochure(ctx context.Context, merchantID string) (*brochure.Brochure, error) {
+	query := `
+		SELECT id, merchant_id, job_id, products, style, format, prompt, engine, file_path, created_at
+		FROM brochures
+		WHERE merchant_id = ?
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	res, err := scanBrochure(r.db.QueryRowContext(ctx, query, merchantID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (r *BrochureRepository) ListBrochures(ctx context.Context, merchantID string, since time.Time, limit int) ([]brochure.Brochure, error) {
+	query := `
+		SELECT id, merchant_id, job_id, products, style, format, prompt, engine, file_path, created_at
+		FROM brochures
+		WHERE merchant_id = ? AND created_at >= ?
+		ORDER BY created_at DESC
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, merchantID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var brochures []brochure.Brochure
+	for rows.Next() {
+		b, err := scanBrochure(rows)
+		if err != nil {
+			return nil, err
+		}
+		brochures = append(brochures, *b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return brochures, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBrochure(row scanner) (*brochure.Brochure, error) {
+	var (
+		res      brochure.Brochure
+		products string
+	)
+	err := row.Scan(
+		&res.ID,
+		&res.MerchantID,
+		&res.JobID,
+		&products,
+		&res.Style,
+		&res.Format,
+		&res.Prompt,
+		&res.Engine,
+		&res.FilePath,
+		&res.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(products), &res.Products); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
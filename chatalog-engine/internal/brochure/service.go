@@ -0,0 +1,52 @@
+package brochure
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+func (s *service) CreateBrochure(ctx context.Context, params CreateBrochureParams) (*Brochure, error) {
+	brochure := Brochure{
+		ID:         uuid.New().String(),
+		MerchantID: params.MerchantID,
+		JobID:      params.JobID,
+		Products:   params.Products,
+		Style:      params.Style,
+		Format:     params.Format,
+		Prompt:     params.Prompt,
+		Engine:     params.Engine,
+		FilePath:   params.FilePath,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.repo.CreateBrochure(ctx, brochure); err != nil {
+		return nil, err
+	}
+	return &brochure, nil
+}
+
+func (s *service) GetLatestBrochure(ctx context.Context, merchantID string) (*Brochure, error) {
+	brochure, err := s.repo.GetLatestBrochure(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if brochure == nil {
+		return nil, ErrBrochureNotFound
+	}
+	return brochure, nil
+}
+
+func (s *service) ListBrochures(ctx context.Context, merchantID string, since time.Time, limit int) ([]Brochure, error) {
+	return s.repo.ListBrochures(ctx, merchantID, since, limit)
+}
@@ -0,0 +1,36 @@
+package channel
+
+import "context"
+
+// Messenger sends messages through a channel.
+type Messenger interface {
+	// Name returns the channel name, e.g. "whatsapp".
+	Name() string
+	// AccountID returns the account the messenger sends from, matching
+	// Message.AccountID of the messages it receives.
+	AccountID() string
+	SendText(ctx context.Context, chatID string, text string) error
+	SendImage(ctx context.Context, chatID string, media Media) error
+	SendDocument(ctx context.Context, chatID string, media Media) error
+}
+
+// PhoneMessenger is a messenger that can start a chat with a phone number,
+// e.g. to notify a merchant outside of a conversation.
+type PhoneMessenger interface {
+	Messenger
+	// PhoneChatID returns the ID of the direct chat with the phone.
+	PhoneChatID(phone string) string
+}
+
+// Handler processes inbound messages independently of the channel they were
+// received from. Replies are sent through the given messenger.
+type Handler interface {
+	HandleMessage(ctx context.Context, messenger Messenger, msg Message)
+}
+
+// Registry keeps track of the messengers that can send replies, so work can be
+// routed to a messenger that was connected after the work was created.
+type Registry interface {
+	RegisterMessenger(messenger Messenger)
+	UnregisterMessenger(messenger Messenger)
+}
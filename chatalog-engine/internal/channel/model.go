@@ -0,0 +1,69 @@
+package channel
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// Message is an inbound message received from any channel.
+type Message struct {
+	ID      string
+	Channel string
+	// AccountID identifies the channel account that received the message when
+	// one process serves several accounts, e.g. the WhatsApp number. It is
+	// empty for channels served by a single account.
+	AccountID  string
+	ChatID     string
+	Sender     Sender
+	IsGroup    bool
+	Text       string
+	Audio      *Attachment
+	Image      *Attachment
+	Document   *Attachment
+	ReceivedAt time.Time
+}
+
+type Sender struct {
+	ID    string
+	Phone string
+	Name  string
+}
+
+// Attachment is an inbound media file. The content is only downloaded from the
+// channel when Open is called.
+type Attachment struct {
+	MimeType string
+	FileName string
+	Caption  string
+	Size     int64
+	Open     func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Media is an outbound media file stored on the local filesystem.
+type Media struct {
+	FilePath string
+	MimeType string
+	FileName string
+	Caption  string
+}
+
+// MimeTypeFromPath guesses the image mimetype from the file extension.
+func MimeTypeFromPath(filePath string) string {
+	mimetype := "image/jpeg" // default
+	if extIdx := strings.LastIndex(filePath, "."); extIdx != -1 {
+		ext := strings.ToLower(filePath[extIdx:])
+		switch ext {
+		case ".png":
+			mimetype = "image/png"
+		case ".jpg", ".jpeg":
+			mimetype = "image/jpeg"
+		case ".gif":
+			mimetype = "image/gif"
+		case ".pdf":
+			mimetype = "application/pdf"
+		}
+	}
+	return mimetype
+}
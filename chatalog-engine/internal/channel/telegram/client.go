@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const DefaultAPIURL = "https://api.telegram.org"
+
+// Client is a minimal Telegram Bot API client. The API URL can point to a
+// local fake server for testing.
+type Client struct {
+	apiURL     string
+	token      string
+	httpClient *http.Client
+}
+
+func NewClient(token string, apiURL string) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &Client{
+		apiURL:     strings.TrimRight(apiURL, "/"),
+		token:      token,
+		httpClient: &http.Client{},
+	}
+}
+
+// GetUpdates long polls for updates after offset, waiting up to timeoutSeconds.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeoutSeconds int) ([]Update, error) {
+	params := url.Values{}
+	params.Set("offset", strconv.FormatInt(offset, 10))
+	params.Set("timeout", strconv.Itoa(timeoutSeconds))
+	params.Set("allowed_updates", `["message"]`)
+
+	var updates []Update
+	if err := c.call(ctx, "getUpdates", params, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+func (c *Client) GetFile(ctx context.Context, fileID string) (*File, error) {
+	params := url.Values{}
+	params.Set("file_id", fileID)
+
+	var file File
+	if err := c.call(ctx, "getFile", params, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// DownloadFile downloads a file previously returned by GetFile.
+func (c *Client) DownloadFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/file/bot%s/%s", c.apiURL, c.token, filePath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download file: http status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) SendMessage(ctx context.Context, chatID string, text string) error {
+	params := url.Values{}
+	params.Set("chat_id", chatID)
+	params.Set("text", text)
+	return c.call(ctx, "sendMessage", params, nil)
+}
+
+func (c *Client) SendPhoto(ctx context.Context, chatID string, filePath string, caption string) error {
+	return c.upload(ctx, "sendPhoto", "photo", chatID, filePath, caption)
+}
+
+func (c *Client) SendDocument(ctx context.Context, chatID string, filePath string, caption string) error {
+	return c.upload(ctx, "sendDocument", "document", chatID, filePath, caption)
+}
+
+// SetWebhook registers url to receive updates. Telegram sends secretToken in
+// the X-Telegram-Bot-Api-Secret-Token header of every webhook request.
+func (c *Client) SetWebhook(ctx context.Context, webhookURL string, secretToken string) error {
+	params := url.Values{}
+	params.Set("url", webhookURL)
+	params.Set("allowed_updates", `["message"]`)
+	if secretToken != "" {
+		params.Set("secret_token", secretToken)
+	}
+	return c.call(ctx, "setWebhook", params, nil)
+}
+
+// DeleteWebhook removes the webhook so updates can be received by polling.
+func (c *Client) DeleteWebhook(ctx context.Context) error {
+	return c.call(ctx, "deleteWebhook", url.Values{}, nil)
+}
+
+func (c *Client) call(ctx context.Context, method string, params url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.methodURL(method), strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.do(req, method, result)
+}
+
+func (c *Client) upload(ctx context.Context, method string, field string, chatID string, filePath string, caption string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("chat_id", chatID); err != nil {
+		return err
+	}
+	if caption != "" {
+		if err := writer.WriteField("caption", caption); err != nil {
+			return err
+		}
+	}
+	part, err := writer.CreateFormFile(field, filepath.Base(filePath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.methodURL(method), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return c.do(req, method, nil)
+}
+
+func (c *Client) do(req *http.Request, method string, result any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("%s: telegram error %d: %s", method, apiResp.ErrorCode, apiResp.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(apiResp.Result, result)
+}
+
+func (c *Client) methodURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", c.apiURL, c.token, method)
+}
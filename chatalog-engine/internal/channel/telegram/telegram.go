@@ -0,0 +1,181 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/channel"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ChannelName = "telegram"
+
+	pollTimeoutSeconds = 30
+	pollRetryDelay     = 3 * time.Second
+)
+
+// Adapter connects a Telegram bot to the channel-agnostic handler. Telegram
+// does not expose phone numbers, so senders are identified by their user ID
+// and mapped to merchants through the merchant_accounts table.
+type Adapter struct {
+	client *Client
+}
+
+func NewAdapter(client *Client) *Adapter {
+	return &Adapter{
+		client: client,
+	}
+}
+
+func (a *Adapter) Name() string {
+	return ChannelName
+}
+
+// AccountID returns an empty string as one process serves a single bot.
+func (a *Adapter) AccountID() string {
+	return ""
+}
+
+// Poll receives updates by long polling until ctx is done. Messages are
+// handled with workCtx, which outlives ctx while the process shuts down.
+func (a *Adapter) Poll(ctx context.Context, workCtx context.Context, handler channel.Handler) error {
+	if err := a.client.DeleteWebhook(ctx); err != nil {
+		return err
+	}
+
+	var offset int64
+	for {
+		updates, err := a.client.GetUpdates(ctx, offset, pollTimeoutSeconds)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Error().Err(err).Msg("error getting telegram updates")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(pollRetryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			a.handleUpdate(workCtx, handler, update)
+		}
+	}
+}
+
+// WebhookHandler returns an http.Handler that receives updates pushed by
+// Telegram. Requests without the matching secret token are rejected.
+func (a *Adapter) WebhookHandler(workCtx context.Context, handler channel.Handler, secretToken string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if secretToken != "" {
+			got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(secretToken)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var update Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		a.handleUpdate(workCtx, handler, update)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func (a *Adapter) handleUpdate(ctx context.Context, handler channel.Handler, update Update) {
+	if update.Message == nil || update.Message.From == nil || update.Message.From.IsBot {
+		return
+	}
+	handler.HandleMessage(ctx, a, a.toMessage(update.Message))
+}
+
+func (a *Adapter) toMessage(m *Message) channel.Message {
+	msg := channel.Message{
+		ID:      strconv.FormatInt(m.MessageID, 10),
+		Channel: ChannelName,
+		ChatID:  strconv.FormatInt(m.Chat.ID, 10),
+		Sender: channel.Sender{
+			ID:   strconv.FormatInt(m.From.ID, 10),
+			Name: m.From.FirstName,
+		},
+		IsGroup:    m.Chat.Type != "private",
+		Text:       m.Text,
+		ReceivedAt: time.Unix(m.Date, 0),
+	}
+
+	switch {
+	case m.Voice != nil:
+		msg.Audio = &channel.Attachment{
+			MimeType: m.Voice.MimeType,
+			Size:     m.Voice.FileSize,
+			Open:     a.opener(m.Voice.FileID),
+		}
+	case m.Audio != nil:
+		msg.Audio = &channel.Attachment{
+			MimeType: m.Audio.MimeType,
+			FileName: m.Audio.FileName,
+			Size:     m.Audio.FileSize,
+			Open:     a.opener(m.Audio.FileID),
+		}
+	}
+	if len(m.Photo) > 0 {
+		// Telegram sends every available size, the last one is the largest.
+		photo := m.Photo[len(m.Photo)-1]
+		msg.Image = &channel.Attachment{
+			MimeType: "image/jpeg",
+			Caption:  m.Caption,
+			Size:     photo.FileSize,
+			Open:     a.opener(photo.FileID),
+		}
+	}
+	if m.Document != nil {
+		msg.Document = &channel.Attachment{
+			MimeType: m.Document.MimeType,
+			FileName: m.Document.FileName,
+			Caption:  m.Caption,
+			Size:     m.Document.FileSize,
+			Open:     a.opener(m.Document.FileID),
+		}
+	}
+
+	return msg
+}
+
+func (a *Adapter) opener(fileID string) func(ctx context.Context) (io.ReadCloser, error) {
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		file, err := a.client.GetFile(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		return a.client.DownloadFile(ctx, file.FilePath)
+	}
+}
+
+func (a *Adapter) SendText(ctx context.Context, chatID string, text string) error {
+	return a.client.SendMessage(ctx, chatID, text)
+}
+
+func (a *Adapter) SendImage(ctx context.Context, chatID string, media channel.Media) error {
+	return a.client.SendPhoto(ctx, chatID, media.FilePath, media.Caption)
+}
+
+func (a *Adapter) SendDocument(ctx context.Context, chatID string, media channel.Media) error {
+	return a.client.SendDocument(ctx, chatID, media.FilePath, media.Caption)
+}
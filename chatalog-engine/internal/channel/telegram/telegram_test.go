@@ -0,0 +1,177 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/channel"
+)
+
+const testToken = "123:abc"
+
+// fakeBotAPI serves the Bot API methods used by the adapter. getUpdates returns
+// the queued updates once, then long polls until the request is cancelled.
+type fakeBotAPI struct {
+	t       *testing.T
+	mu      sync.Mutex
+	updates []Update
+	offsets []string
+	sent    chan map[string]string
+	// polling receives the offset of every getUpdates call left waiting.
+	polling chan string
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(result any) {
+		body, _ := json.Marshal(result)
+		json.NewEncoder(w).Encode(apiResponse{OK: true, Result: body})
+	}
+
+	switch r.URL.Path {
+	case "/bot" + testToken + "/deleteWebhook":
+		reply(true)
+	case "/bot" + testToken + "/getUpdates":
+		f.mu.Lock()
+		f.offsets = append(f.offsets, r.FormValue("offset"))
+		updates := f.updates
+		f.updates = nil
+		f.mu.Unlock()
+		if len(updates) == 0 {
+			f.polling <- r.FormValue("offset")
+			<-r.Context().Done()
+			return
+		}
+		reply(updates)
+	case "/bot" + testToken + "/sendMessage":
+		f.sent <- map[string]string{"method": "sendMessage", "chat_id": r.FormValue("chat_id"), "text": r.FormValue("text")}
+		reply(map[string]any{"message_id": 10})
+	case "/bot" + testToken + "/sendPhoto":
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			f.t.Errorf("parse sendPhoto form: %v", err)
+		}
+		_, header, err := r.FormFile("photo")
+		if err != nil {
+			f.t.Errorf("sendPhoto without photo: %v", err)
+			reply(false)
+			return
+		}
+		f.sent <- map[string]string{"method": "sendPhoto", "chat_id": r.FormValue("chat_id"), "file": header.Filename}
+		reply(map[string]any{"message_id": 11})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(apiResponse{OK: false, ErrorCode: 404, Description: "Not Found"})
+	}
+}
+
+// replyHandler answers every message with a text and the brochure image.
+type replyHandler struct {
+	t        *testing.T
+	brochure string
+	mu       sync.Mutex
+	handled  []channel.Message
+}
+
+func (h *replyHandler) HandleMessage(ctx context.Context, messenger channel.Messenger, msg channel.Message) {
+	h.mu.Lock()
+	h.handled = append(h.handled, msg)
+	h.mu.Unlock()
+
+	if err := messenger.SendText(ctx, msg.ChatID, "Generating brochure..."); err != nil {
+		h.t.Errorf("SendText: %v", err)
+	}
+	if err := messenger.SendImage(ctx, msg.ChatID, channel.Media{FilePath: h.brochure}); err != nil {
+		h.t.Errorf("SendImage: %v", err)
+	}
+}
+
+func TestAdapterPollAndReply(t *testing.T) {
+	brochure := filepath.Join(t.TempDir(), "brochure.png")
+	if err := os.WriteFile(brochure, []byte("\x89PNG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeBotAPI{
+		t: t,
+		updates: []Update{
+			{UpdateID: 7, Message: &Message{
+				MessageID: 42,
+				From:      &User{ID: 555, IsBot: true, FirstName: "Other bot"},
+				Chat:      Chat{ID: 555, Type: "private"},
+				Text:      "ignored",
+			}},
+			{UpdateID: 8, Message: &Message{
+				MessageID: 43,
+				From:      &User{ID: 1001, FirstName: "Budi"},
+				Chat:      Chat{ID: 1001, Type: "private"},
+				Date:      1700000000,
+				Text:      "buatkan brosur kopi",
+			}},
+		},
+		sent:    make(chan map[string]string, 4),
+		polling: make(chan string, 1),
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	handler := &replyHandler{t: t, brochure: brochure}
+	adapter := NewAdapter(NewClient(testToken, server.URL))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	polled := make(chan error, 1)
+	go func() {
+		polled <- adapter.Poll(ctx, context.Background(), handler)
+	}()
+
+	var sent []map[string]string
+	for len(sent) < 2 {
+		select {
+		case s := <-api.sent:
+			sent = append(sent, s)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for replies, got %v", sent)
+		}
+	}
+	select {
+	case offset := <-api.polling:
+		if offset != "9" {
+			t.Errorf("getUpdates offset after the updates = %s, want 9", offset)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the next getUpdates")
+	}
+	cancel()
+	if err := <-polled; err != nil {
+		t.Fatalf("Poll returned %v", err)
+	}
+
+	if len(handler.handled) != 1 {
+		t.Fatalf("handled %d messages, want 1 (bot messages are ignored)", len(handler.handled))
+	}
+	msg := handler.handled[0]
+	if msg.ID != "43" || msg.ChatID != "1001" || msg.Sender.ID != "1001" || msg.Text != "buatkan brosur kopi" || msg.IsGroup {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if msg.Channel != ChannelName || msg.Sender.Phone != "" {
+		t.Errorf("message must come from %s without a phone, got %+v", ChannelName, msg)
+	}
+
+	if sent[0]["method"] != "sendMessage" || sent[0]["chat_id"] != "1001" || sent[0]["text"] != "Generating brochure..." {
+		t.Errorf("unexpected text reply %v", sent[0])
+	}
+	if sent[1]["method"] != "sendPhoto" || sent[1]["chat_id"] != "1001" || sent[1]["file"] != "brochure.png" {
+		t.Errorf("unexpected photo reply %v", sent[1])
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.offsets) != 2 || api.offsets[0] != "0" {
+		t.Errorf("getUpdates offsets = %v, want 0 then 9", api.offsets)
+	}
+}
@@ -0,0 +1,210 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+type LoginState string
+
+const (
+	LoginStateLoggedOut LoginState = "logged_out"
+	LoginStateWaiting   LoginState = "waiting_for_scan"
+	LoginStateTimeout   LoginState = "timeout"
+	LoginStateLoggedIn  LoginState = "logged_in"
+	LoginStateError     LoginState = "error"
+)
+
+var ErrNotWaitingForLogin = errors.New("client is not waiting for a login")
+
+type LoginStatus struct {
+	State       LoginState `json:"state"`
+	JID         string     `json:"jid,omitempty"`
+	Connected   bool       `json:"connected"`
+	QRAvailable bool       `json:"qr_available"`
+	Error       string     `json:"error,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Login tracks the login of a whatsmeow client, so the QR code and pairing
+// code can be served over HTTP instead of being printed to the log.
+type Login struct {
+	client *whatsmeow.Client
+
+	mu          sync.Mutex
+	status      LoginStatus
+	qrCode      string
+	subscribers map[chan LoginStatus]struct{}
+}
+
+func NewLogin(client *whatsmeow.Client) *Login {
+	l := &Login{
+		client:      client,
+		subscribers: map[chan LoginStatus]struct{}{},
+	}
+	l.status = LoginStatus{State: LoginStateLoggedOut, UpdatedAt: time.Now()}
+	if client.Store.ID != nil {
+		l.status.State = LoginStateLoggedIn
+		l.status.JID = client.Store.ID.String()
+	}
+	client.AddEventHandler(l.handleEvent)
+	return l
+}
+
+// Start connects the client. When the device is not paired yet, it starts
+// emitting QR codes until one is scanned, a pairing code is used or the codes
+// run out.
+func (l *Login) Start(ctx context.Context) error {
+	if l.client.Store.ID != nil {
+		return l.client.Connect()
+	}
+
+	if l.client.IsConnected() {
+		l.client.Disconnect()
+	}
+	qrChan, err := l.client.GetQRChannel(ctx)
+	if err != nil {
+		return err
+	}
+	if err := l.client.Connect(); err != nil {
+		return err
+	}
+
+	go func() {
+		for evt := range qrChan {
+			switch evt.Event {
+			case whatsmeow.QRChannelEventCode:
+				l.update(func(s *LoginStatus) {
+					s.State = LoginStateWaiting
+					s.QRAvailable = true
+					s.Error = ""
+				}, evt.Code)
+			case whatsmeow.QRChannelSuccess.Event:
+				// The PairSuccess event updates the status.
+			case whatsmeow.QRChannelTimeout.Event:
+				l.update(func(s *LoginStatus) {
+					s.State = LoginStateTimeout
+					s.QRAvailable = false
+				}, "")
+			default:
+				errMessage := evt.Event
+				if evt.Error != nil {
+					errMessage = evt.Error.Error()
+				}
+				l.update(func(s *LoginStatus) {
+					s.State = LoginStateError
+					s.QRAvailable = false
+					s.Error = errMessage
+				}, "")
+			}
+			log.Info().Str("event", evt.Event).Msg("WhatsApp login event")
+		}
+	}()
+	return nil
+}
+
+// PairPhone requests a pairing code for the phone number, which the user
+// enters on their phone instead of scanning the QR code.
+func (l *Login) PairPhone(ctx context.Context, phone string) (string, error) {
+	if l.Status().State != LoginStateWaiting {
+		return "", ErrNotWaitingForLogin
+	}
+	return l.client.PairPhone(ctx, phone, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+}
+
+func (l *Login) Status() LoginStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status := l.status
+	status.Connected = l.client.IsConnected()
+	return status
+}
+
+// QRCode returns the current QR code content, or an empty string when there is
+// no QR code to scan.
+func (l *Login) QRCode() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.qrCode
+}
+
+// Subscribe returns a channel that receives every status change. The returned
+// function must be called to unsubscribe.
+func (l *Login) Subscribe() (<-chan LoginStatus, func()) {
+	ch := make(chan LoginStatus, 1)
+	l.mu.Lock()
+	l.subscribers[ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subscribers, ch)
+		l.mu.Unlock()
+	}
+}
+
+func (l *Login) handleEvent(evt any) {
+	switch v := evt.(type) {
+	case *events.PairSuccess:
+		l.update(func(s *LoginStatus) {
+			s.State = LoginStateLoggedIn
+			s.JID = v.ID.String()
+			s.QRAvailable = false
+			s.Error = ""
+		}, "")
+	case *events.PairError:
+		l.update(func(s *LoginStatus) {
+			s.State = LoginStateError
+			s.Error = fmt.Sprintf("pairing failed: %v", v.Error)
+		}, "")
+	case *events.Connected:
+		l.update(func(s *LoginStatus) {
+			if id := l.client.Store.ID; id != nil {
+				s.State = LoginStateLoggedIn
+				s.JID = id.String()
+				s.QRAvailable = false
+			}
+		}, "")
+	case *events.Disconnected:
+		l.update(func(s *LoginStatus) {}, l.QRCode())
+	case *events.LoggedOut:
+		l.update(func(s *LoginStatus) {
+			s.State = LoginStateLoggedOut
+			s.JID = ""
+			s.QRAvailable = false
+		}, "")
+	}
+}
+
+func (l *Login) update(fn func(s *LoginStatus), qrCode string) {
+	l.mu.Lock()
+	fn(&l.status)
+	l.status.UpdatedAt = time.Now()
+	l.qrCode = qrCode
+	status := l.status
+	subscribers := make([]chan LoginStatus, 0, len(l.subscribers))
+	for ch := range l.subscribers {
+		subscribers = append(subscribers, ch)
+	}
+	l.mu.Unlock()
+
+	status.Connected = l.client.IsConnected()
+	for _, ch := range subscribers {
+		// Drop the stale status if the subscriber did not read it yet.
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- status:
+		default:
+		}
+	}
+}
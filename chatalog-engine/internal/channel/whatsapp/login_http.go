@@ -0,0 +1,187 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// servePage serves the login page. The page only uses relative URLs, so it
+// works wherever the login endpoints are mounted.
+func (l *Login) servePage(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		// Keep the token out of the address bar and access logs.
+		http.SetCookie(w, &http.Cookie{
+			Name:     adminCookieName,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+			Secure:   r.TLS != nil,
+		})
+		w.Header().Set("Location", path.Base(r.URL.Path))
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	loginPage.Execute(w, nil)
+}
+
+func (l *Login) serveQR(w http.ResponseWriter, r *http.Request) {
+	code := l.QRCode()
+	if code == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no qr code available"})
+		return
+	}
+
+	png, err := qrcode.Encode(code, qrcode.Medium, 320)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+func (l *Login) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+
+	updates, unsubscribe := l.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(status LoginStatus) {
+		b, _ := json.Marshal(status)
+		fmt.Fprintf(w, "event: status\ndata: %s\n\n", b)
+		flusher.Flush()
+	}
+	send(l.Status())
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case status := <-updates:
+			send(status)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func (l *Login) serveStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, l.Status())
+}
+
+func (l *Login) servePair(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Phone string `json:"phone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	phone := strings.TrimPrefix(strings.TrimSpace(req.Phone), "+")
+	if phone == "" || strings.Trim(phone, "0123456789") != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "phone must contain digits only, including the country code"})
+		return
+	}
+
+	code, err := l.PairPhone(r.Context(), phone)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, ErrNotWaitingForLogin) {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"code": code})
+}
+
+func (l *Login) serveRestart(w http.ResponseWriter, r *http.Request) {
+	if l.Status().State == LoginStateLoggedIn {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "already logged in"})
+		return
+	}
+
+	// The QR channel lives as long as the login, not as long as this request.
+	if err := l.Start(context.WithoutCancel(r.Context())); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, l.Status())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Chatalog WhatsApp login</title>
+<style>
+body { font-family: sans-serif; max-width: 420px; margin: 40px auto; color: #222; }
+img { width: 320px; height: 320px; border: 1px solid #ddd; }
+#code { font-size: 28px; letter-spacing: 4px; font-weight: bold; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<h1>WhatsApp login</h1>
+<p>Status: <strong id="state">loading</strong> <span id="jid"></span></p>
+<p id="error"></p>
+<img id="qr" class="hidden" alt="WhatsApp QR code">
+<button id="restart" class="hidden">Show a new QR code</button>
+<h2>Pair with phone number</h2>
+<form id="pair">
+<input name="phone" placeholder="6281234567890" required>
+<button type="submit">Get pairing code</button>
+</form>
+<p id="code"></p>
+<script>
+const qr = document.getElementById("qr");
+const render = (s) => {
+  document.getElementById("state").textContent = s.state;
+  document.getElementById("jid").textContent = s.jid || "";
+  document.getElementById("error").textContent = s.error || "";
+  qr.classList.toggle("hidden", !s.qr_available);
+  if (s.qr_available) qr.src = "login/qr.png?t=" + Date.now();
+  document.getElementById("restart").classList.toggle("hidden", s.state !== "timeout" && s.state !== "error");
+};
+new EventSource("login/events").addEventListener("status", (e) => render(JSON.parse(e.data)));
+document.getElementById("restart").onclick = () => fetch("login/restart", {method: "POST"});
+document.getElementById("pair").onsubmit = async (e) => {
+  e.preventDefault();
+  const res = await fetch("login/pair", {method: "POST", headers: {"Content-Type": "application/json"}, body: JSON.stringify({phone: e.target.phone.value})});
+  const body = await res.json();
+  document.getElementById("code").textContent = body.code || body.error;
+};
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,222 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/defryfazz/fazztalog/internal/channel"
+	"github.com/rs/zerolog/log"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+// Device is one WhatsApp number served by the Manager. Paired devices are
+// identified by their phone number, devices waiting for a login by a random
+// ID until the process restarts.
+type Device struct {
+	ID      string
+	Client  *whatsmeow.Client
+	Adapter *Adapter
+	Login   *Login
+}
+
+// Phone returns the phone number of the device, or an empty string while the
+// device is not paired.
+func (d *Device) Phone() string {
+	return d.Adapter.AccountID()
+}
+
+type ManagerParams struct {
+	Container *sqlstore.Container
+	Handler   channel.Handler
+	Registry  channel.Registry
+}
+
+// Manager serves every device stored in the whatsmeow container from one
+// process. Devices can be added and removed at runtime.
+type Manager struct {
+	container *sqlstore.Container
+	handler   channel.Handler
+	registry  channel.Registry
+	// workCtx is passed to the handler for every incoming message.
+	workCtx context.Context
+
+	mu      sync.Mutex
+	devices map[string]*Device
+}
+
+func NewManager(workCtx context.Context, params ManagerParams) *Manager {
+	return &Manager{
+		container: params.Container,
+		handler:   params.Handler,
+		registry:  params.Registry,
+		workCtx:   workCtx,
+		devices:   map[string]*Device{},
+	}
+}
+
+// LoadDevices connects every device stored in the container.
+func (m *Manager) LoadDevices(ctx context.Context) error {
+	deviceStores, err := m.container.GetAllDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, deviceStore := range deviceStores {
+		d := m.addDevice(deviceStore)
+		if err := d.Login.Start(m.workCtx); err != nil {
+			log.Error().Err(err).Str("device_id", d.ID).Msg("error connecting WhatsApp device")
+		}
+	}
+	return nil
+}
+
+// AddDevice creates a new device and starts its login. The device is only
+// persisted once it is paired.
+func (m *Manager) AddDevice() (*Device, error) {
+	d := m.addDevice(m.container.NewDevice())
+	if err := d.Login.Start(m.workCtx); err != nil {
+		m.mu.Lock()
+		delete(m.devices, d.ID)
+		m.mu.Unlock()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (m *Manager) addDevice(deviceStore *store.Device) *Device {
+	client := whatsmeow.NewClient(deviceStore, nil)
+	d := &Device{
+		Client:  client,
+		Adapter: NewAdapter(client),
+		Login:   NewLogin(client),
+	}
+	if deviceStore.ID != nil {
+		d.ID = deviceStore.ID.User
+		m.registry.RegisterMessenger(d.Adapter)
+	} else {
+		d.ID = newPendingID()
+	}
+
+	client.AddEventHandler(d.Adapter.EventHandler(m.workCtx, m.handler))
+	client.AddEventHandler(func(evt any) {
+		switch v := evt.(type) {
+		case *events.PairSuccess:
+			log.Info().Str("device_id", d.ID).Str("phone", v.ID.User).Msg("WhatsApp device paired")
+			m.registry.RegisterMessenger(d.Adapter)
+		case *events.LoggedOut:
+			log.Warn().Str("device_id", d.ID).Stringer("reason", v.Reason).Msg("WhatsApp device was logged out")
+			m.registry.UnregisterMessenger(d.Adapter)
+		}
+	})
+
+	m.mu.Lock()
+	m.devices[d.ID] = d
+	m.mu.Unlock()
+	return d
+}
+
+// RemoveDevice logs the device out and deletes it from the container. The id
+// can be the device ID or its phone number.
+func (m *Manager) RemoveDevice(ctx context.Context, id string) error {
+	d, ok := m.Device(id)
+	if !ok {
+		return ErrDeviceNotFound
+	}
+
+	m.registry.UnregisterMessenger(d.Adapter)
+	if d.Client.Store.ID != nil {
+		// Logout deletes the device from the container, but needs a connection.
+		if err := d.Client.Logout(ctx); err != nil {
+			log.Warn().Err(err).Str("device_id", d.ID).Msg("error logging out WhatsApp device, deleting it locally")
+			d.Client.Disconnect()
+			if err := d.Client.Store.Delete(ctx); err != nil {
+				return err
+			}
+		}
+	} else {
+		d.Client.Disconnect()
+	}
+
+	m.mu.Lock()
+	delete(m.devices, d.ID)
+	m.mu.Unlock()
+	return nil
+}
+
+// Device returns the device with the given ID or phone number.
+func (m *Manager) Device(id string) (*Device, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d, ok := m.devices[id]; ok {
+		return d, true
+	}
+	for _, d := range m.devices {
+		if d.Phone() == id {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// Devices returns all devices ordered by ID.
+func (m *Manager) Devices() []*Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	devices := make([]*Device, 0, len(m.devices))
+	for _, d := range m.devices {
+		devices = append(devices, d)
+	}
+	slices.SortFunc(devices, func(a, b *Device) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return devices
+}
+
+// Ready reports an error when a paired device is not connected, or when no
+// device is paired at all. Devices waiting for a login are ignored.
+func (m *Manager) Ready() error {
+	var connected int
+	var disconnected []string
+	for _, d := range m.Devices() {
+		if d.Client.Store.ID == nil {
+			continue
+		}
+		if d.Client.IsConnected() && d.Client.IsLoggedIn() {
+			connected++
+		} else {
+			disconnected = append(disconnected, d.ID)
+		}
+	}
+
+	if len(disconnected) > 0 {
+		return fmt.Errorf("devices not connected: %s", strings.Join(disconnected, ", "))
+	}
+	if connected == 0 {
+		return errors.New("no device is logged in")
+	}
+	return nil
+}
+
+// Disconnect disconnects every device without logging it out.
+func (m *Manager) Disconnect() {
+	for _, d := range m.Devices() {
+		d.Client.Disconnect()
+	}
+}
+
+func newPendingID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return "new-" + hex.EncodeToString(b)
+}
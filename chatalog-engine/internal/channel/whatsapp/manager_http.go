@@ -0,0 +1,115 @@
+package whatsapp
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const adminCookieName = "chatalog_admin_token"
+
+type deviceResponse struct {
+	ID       string      `json:"id"`
+	Phone    string      `json:"phone,omitempty"`
+	Status   LoginStatus `json:"status"`
+	LoginURL string      `json:"login_url"`
+}
+
+// AdminHandler serves the device management endpoints:
+//
+//	GET    /devices                         list devices and their login status
+//	POST   /devices                         add a device and start its login
+//	DELETE /devices/{id}                    log a device out and delete it
+//	GET    /devices/{id}/login              HTML page showing the QR code and a pairing code form
+//	GET    /devices/{id}/login/qr.png       current QR code as PNG
+//	GET    /devices/{id}/login/events       login status stream (server-sent events)
+//	GET    /devices/{id}/login/status       login status as JSON
+//	POST   /devices/{id}/login/pair         request a pairing code for {"phone": "628..."}
+//	POST   /devices/{id}/login/restart      start a new login after the QR codes timed out
+//
+// The {id} is the device ID or its phone number. Every endpoint requires
+// adminToken, as a bearer token or as the cookie set by opening a login page
+// with ?token=<adminToken> once.
+func (m *Manager) AdminHandler(adminToken string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /devices", m.serveDevices)
+	mux.HandleFunc("POST /devices", m.serveAddDevice)
+	mux.HandleFunc("DELETE /devices/{id}", m.serveRemoveDevice)
+	mux.HandleFunc("GET /devices/{id}/login", m.loginRoute((*Login).servePage))
+	mux.HandleFunc("GET /devices/{id}/login/qr.png", m.loginRoute((*Login).serveQR))
+	mux.HandleFunc("GET /devices/{id}/login/events", m.loginRoute((*Login).serveEvents))
+	mux.HandleFunc("GET /devices/{id}/login/status", m.loginRoute((*Login).serveStatus))
+	mux.HandleFunc("POST /devices/{id}/login/pair", m.loginRoute((*Login).servePair))
+	mux.HandleFunc("POST /devices/{id}/login/restart", m.loginRoute((*Login).serveRestart))
+	return requireAdmin(adminToken, mux)
+}
+
+func requireAdmin(adminToken string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			if cookie, err := r.Cookie(adminCookieName); err == nil {
+				token = cookie.Value
+			}
+		}
+		if token == "" && strings.HasSuffix(r.URL.Path, "/login") {
+			token = r.URL.Query().Get("token")
+		}
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *Manager) loginRoute(serve func(l *Login, w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, ok := m.Device(r.PathValue("id"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": ErrDeviceNotFound.Error()})
+			return
+		}
+		serve(d.Login, w, r)
+	}
+}
+
+func (m *Manager) serveDevices(w http.ResponseWriter, r *http.Request) {
+	devices := m.Devices()
+	data := make([]deviceResponse, 0, len(devices))
+	for _, d := range devices {
+		data = append(data, toDeviceResponse(d))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func (m *Manager) serveAddDevice(w http.ResponseWriter, r *http.Request) {
+	d, err := m.AddDevice()
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, toDeviceResponse(d))
+}
+
+func (m *Manager) serveRemoveDevice(w http.ResponseWriter, r *http.Request) {
+	if err := m.RemoveDevice(r.Context(), r.PathValue("id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrDeviceNotFound) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toDeviceResponse(d *Device) deviceResponse {
+	return deviceResponse{
+		ID:       d.ID,
+		Phone:    d.Phone(),
+		Status:   d.Login.Status(),
+		LoginURL: "/devices/" + d.ID + "/login",
+	}
+}
@@ -0,0 +1,249 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/defryfazz/fazztalog/internal/channel"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+const ChannelName = "whatsapp"
+
+// Adapter connects a whatsmeow client to the channel-agnostic handler.
+type Adapter struct {
+	client *whatsmeow.Client
+}
+
+func NewAdapter(client *whatsmeow.Client) *Adapter {
+	return &Adapter{
+		client: client,
+	}
+}
+
+func (a *Adapter) Name() string {
+	return ChannelName
+}
+
+// AccountID returns the phone number of the device, or an empty string while
+// the device is not paired.
+func (a *Adapter) AccountID() string {
+	if id := a.client.Store.ID; id != nil {
+		return id.User
+	}
+	return ""
+}
+
+func (a *Adapter) PhoneChatID(phone string) string {
+	return types.NewJID(phone, types.DefaultUserServer).String()
+}
+
+// EventHandler returns a whatsmeow event handler that forwards incoming
+// messages to handler.
+func (a *Adapter) EventHandler(ctx context.Context, handler channel.Handler) whatsmeow.EventHandler {
+	return func(evt any) {
+		switch v := evt.(type) {
+		case *events.Message:
+			handler.HandleMessage(ctx, a, a.toMessage(v))
+		}
+	}
+}
+
+func (a *Adapter) toMessage(evt *events.Message) channel.Message {
+	senderJID := evt.Info.Sender.ToNonAD()
+	msg := channel.Message{
+		ID:        evt.Info.ID,
+		Channel:   ChannelName,
+		AccountID: a.AccountID(),
+		ChatID:    evt.Info.Chat.String(),
+		Sender: channel.Sender{
+			ID:    senderJID.String(),
+			Phone: GetPhoneFromJID(senderJID.String()),
+			Name:  evt.Info.PushName,
+		},
+		IsGroup:    evt.Info.IsGroup,
+		ReceivedAt: evt.Info.Timestamp,
+	}
+
+	if hasActualText(evt) {
+		msg.Text = getMessage(evt)
+	}
+	if audio := evt.Message.GetAudioMessage(); audio != nil {
+		msg.Audio = &channel.Attachment{
+			MimeType: audio.GetMimetype(),
+			Size:     int64(audio.GetFileLength()),
+			Open:     a.opener(audio),
+		}
+	}
+	if image := evt.Message.GetImageMessage(); image != nil {
+		msg.Image = &channel.Attachment{
+			MimeType: image.GetMimetype(),
+			Caption:  image.GetCaption(),
+			Size:     int64(image.GetFileLength()),
+			Open:     a.opener(image),
+		}
+	}
+	if document := evt.Message.GetDocumentMessage(); document != nil {
+		msg.Document = &channel.Attachment{
+			MimeType: document.GetMimetype(),
+			FileName: document.GetFileName(),
+			Caption:  document.GetCaption(),
+			Size:     int64(document.GetFileLength()),
+			Open:     a.opener(document),
+		}
+	}
+
+	return msg
+}
+
+func (a *Adapter) opener(media whatsmeow.DownloadableMessage) func(ctx context.Context) (io.ReadCloser, error) {
+	return func(ctx context.Context) (io.ReadCloser, error) {
+		data, err := a.client.Download(ctx, media)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+func (a *Adapter) SendText(ctx context.Context, chatID string, text string) error {
+	jid, err := types.ParseJID(chatID)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.client.SendMessage(ctx, jid, &waE2E.Message{
+		Conversation: proto.String(text),
+	})
+	return err
+}
+
+func (a *Adapter) SendImage(ctx context.Context, chatID string, media channel.Media) error {
+	jid, err := types.ParseJID(chatID)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(media.FilePath)
+	if err != nil {
+		return err
+	}
+
+	upload, err := a.client.Upload(ctx, data, whatsmeow.MediaImage)
+	if err != nil {
+		return err
+	}
+
+	mimetype := media.MimeType
+	if mimetype == "" {
+		mimetype = channel.MimeTypeFromPath(media.FilePath)
+	}
+
+	_, err = a.client.SendMessage(ctx, jid, &waE2E.Message{
+		ImageMessage: &waE2E.ImageMessage{
+			URL:           proto.String(upload.URL),
+			DirectPath:    proto.String(upload.DirectPath),
+			MediaKey:      upload.MediaKey,
+			FileLength:    proto.Uint64(uint64(len(data))),
+			Mimetype:      proto.String(mimetype),
+			FileEncSHA256: upload.FileEncSHA256,
+			FileSHA256:    upload.FileSHA256,
+			Caption:       optionalString(media.Caption),
+		},
+	})
+	return err
+}
+
+func (a *Adapter) SendDocument(ctx context.Context, chatID string, media channel.Media) error {
+	jid, err := types.ParseJID(chatID)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(media.FilePath)
+	if err != nil {
+		return err
+	}
+
+	upload, err := a.client.Upload(ctx, data, whatsmeow.MediaDocument)
+	if err != nil {
+		return err
+	}
+
+	mimetype := media.MimeType
+	if mimetype == "" {
+		mimetype = channel.MimeTypeFromPath(media.FilePath)
+	}
+	fileName := media.FileName
+	if fileName == "" {
+		fileName = filepath.Base(media.FilePath)
+	}
+
+	_, err = a.client.SendMessage(ctx, jid, &waE2E.Message{
+		DocumentMessage: &waE2E.DocumentMessage{
+			URL:           proto.String(upload.URL),
+			DirectPath:    proto.String(upload.DirectPath),
+			MediaKey:      upload.MediaKey,
+			FileLength:    proto.Uint64(uint64(len(data))),
+			Mimetype:      proto.String(mimetype),
+			FileName:      proto.String(fileName),
+			FileEncSHA256: upload.FileEncSHA256,
+			FileSHA256:    upload.FileSHA256,
+			Caption:       optionalString(media.Caption),
+		},
+	})
+	return err
+}
+
+func getMessage(evt *events.Message) string {
+	if evt.Message.GetConversation() != "" {
+		return evt.Message.GetConversation()
+	}
+	if evt.Message.GetExtendedTextMessage() != nil && evt.Message.GetExtendedTextMessage().Text != nil {
+		return evt.Message.GetExtendedTextMessage().GetText()
+	}
+	return ""
+}
+
+func hasActualText(evt *events.Message) bool {
+	text := getMessage(evt)
+	if text == "" {
+		return false
+	}
+	ext := evt.Message.GetExtendedTextMessage()
+	if ext != nil && ext.GetContextInfo() != nil {
+		for _, jid := range ext.GetContextInfo().GetMentionedJID() {
+			if idx := strings.Index(jid, "@"); idx > 0 {
+				number := jid[:idx]
+				text = strings.ReplaceAll(text, "@"+number, "")
+			}
+		}
+	}
+	return strings.TrimSpace(text) != ""
+}
+
+// GetPhoneFromJID returns the user part of a JID, which is the phone number
+// for regular WhatsApp users.
+func GetPhoneFromJID(jid string) string {
+	splittedJID := strings.Split(jid, "@")
+	if len(splittedJID) < 2 {
+		return ""
+	}
+
+	return splittedJID[0]
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return proto.String(s)
+}
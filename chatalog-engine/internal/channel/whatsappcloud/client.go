@@ -0,0 +1,210 @@
+package whatsappcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultGraphURL   = "https://graph.facebook.com"
+	DefaultAPIVersion = "v21.0"
+)
+
+// Client is a minimal client of the WhatsApp Business Cloud API. The Graph URL
+// can point to a local stub for testing.
+type Client struct {
+	graphURL      string
+	apiVersion    string
+	accessToken   string
+	phoneNumberID string
+	httpClient    *http.Client
+}
+
+type ClientParams struct {
+	GraphURL      string
+	APIVersion    string
+	AccessToken   string
+	PhoneNumberID string
+}
+
+func NewClient(params ClientParams) *Client {
+	if params.GraphURL == "" {
+		params.GraphURL = DefaultGraphURL
+	}
+	if params.APIVersion == "" {
+		params.APIVersion = DefaultAPIVersion
+	}
+	return &Client{
+		graphURL:      strings.TrimRight(params.GraphURL, "/"),
+		apiVersion:    params.APIVersion,
+		accessToken:   params.AccessToken,
+		phoneNumberID: params.PhoneNumberID,
+		httpClient:    &http.Client{},
+	}
+}
+
+func (c *Client) PhoneNumberID() string {
+	return c.phoneNumberID
+}
+
+func (c *Client) SendText(ctx context.Context, to string, text string) error {
+	return c.sendMessage(ctx, map[string]any{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "text",
+		"text":              map[string]any{"body": text},
+	})
+}
+
+func (c *Client) SendImage(ctx context.Context, to string, mediaID string, caption string) error {
+	image := map[string]any{"id": mediaID}
+	if caption != "" {
+		image["caption"] = caption
+	}
+	return c.sendMessage(ctx, map[string]any{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "image",
+		"image":             image,
+	})
+}
+
+func (c *Client) SendDocument(ctx context.Context, to string, mediaID string, fileName string, caption string) error {
+	document := map[string]any{"id": mediaID, "filename": fileName}
+	if caption != "" {
+		document["caption"] = caption
+	}
+	return c.sendMessage(ctx, map[string]any{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "document",
+		"document":          document,
+	})
+}
+
+// UploadMedia uploads a local file and returns its media ID.
+func (c *Client) UploadMedia(ctx context.Context, filePath string, mimeType string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("messaging_product", "whatsapp"); err != nil {
+		return "", err
+	}
+	if err := writer.WriteField("type", mimeType); err != nil {
+		return "", err
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, filepath.Base(filePath)))
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(c.phoneNumberID, "media"), &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var res uploadResponse
+	if err := c.do(req, &res); err != nil {
+		return "", fmt.Errorf("upload media: %w", err)
+	}
+	return res.ID, nil
+}
+
+// DownloadMedia resolves the URL of an inbound media and downloads it.
+func (c *Client) DownloadMedia(ctx context.Context, mediaID string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(mediaID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var info mediaInfo
+	if err := c.do(req, &info); err != nil {
+		return nil, fmt.Errorf("get media: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, info.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download media: http status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) sendMessage(ctx context.Context, payload map[string]any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(c.phoneNumberID, "messages"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := c.do(req, nil); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) do(req *http.Request, result any) error {
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var graphErr graphError
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		if json.Unmarshal(b, &graphErr) == nil && graphErr.Error.Message != "" {
+			return fmt.Errorf("graph error %d: %s", graphErr.Error.Code, graphErr.Error.Message)
+		}
+		return fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (c *Client) url(parts ...string) string {
+	return c.graphURL + "/" + c.apiVersion + "/" + strings.Join(parts, "/")
+}
@@ -0,0 +1,77 @@
+package whatsappcloud
+
+// webhookPayload is the body of the notifications sent by the Cloud API.
+type webhookPayload struct {
+	Object string         `json:"object"`
+	Entry  []webhookEntry `json:"entry"`
+}
+
+type webhookEntry struct {
+	ID      string          `json:"id"`
+	Changes []webhookChange `json:"changes"`
+}
+
+type webhookChange struct {
+	Field string       `json:"field"`
+	Value webhookValue `json:"value"`
+}
+
+type webhookValue struct {
+	MessagingProduct string           `json:"messaging_product"`
+	Metadata         webhookMetadata  `json:"metadata"`
+	Contacts         []webhookContact `json:"contacts"`
+	Messages         []webhookMessage `json:"messages"`
+}
+
+type webhookMetadata struct {
+	DisplayPhoneNumber string `json:"display_phone_number"`
+	PhoneNumberID      string `json:"phone_number_id"`
+}
+
+type webhookContact struct {
+	WaID    string `json:"wa_id"`
+	Profile struct {
+		Name string `json:"name"`
+	} `json:"profile"`
+}
+
+type webhookMessage struct {
+	ID        string        `json:"id"`
+	From      string        `json:"from"`
+	Timestamp string        `json:"timestamp"`
+	Type      string        `json:"type"`
+	Text      *webhookText  `json:"text"`
+	Audio     *webhookMedia `json:"audio"`
+	Image     *webhookMedia `json:"image"`
+	Document  *webhookMedia `json:"document"`
+}
+
+type webhookText struct {
+	Body string `json:"body"`
+}
+
+type webhookMedia struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+}
+
+type mediaInfo struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	FileSize int64  `json:"file_size"`
+}
+
+type uploadResponse struct {
+	ID string `json:"id"`
+}
+
+type graphError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
@@ -0,0 +1,221 @@
+package whatsappcloud
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/channel"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ChannelName = "whatsapp_cloud"
+
+	maxWebhookBodySize = 1 << 20
+)
+
+// Adapter connects the official WhatsApp Business Cloud API to the
+// channel-agnostic handler. Messages are received through a webhook.
+type Adapter struct {
+	client *Client
+}
+
+func NewAdapter(client *Client) *Adapter {
+	return &Adapter{
+		client: client,
+	}
+}
+
+func (a *Adapter) Name() string {
+	return ChannelName
+}
+
+// AccountID returns an empty string as one process serves a single phone number ID.
+func (a *Adapter) AccountID() string {
+	return ""
+}
+
+// PhoneChatID returns the phone itself, the Cloud API addresses users by phone.
+func (a *Adapter) PhoneChatID(phone string) string {
+	return phone
+}
+
+// WebhookHandler returns an http.Handler for the Cloud API webhook. GET
+// requests answer the subscription verification with verifyToken, POST
+// requests must be signed with appSecret in the X-Hub-Signature-256 header.
+func (a *Adapter) WebhookHandler(workCtx context.Context, handler channel.Handler, verifyToken string, appSecret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			a.verifySubscription(w, r, verifyToken)
+		case http.MethodPost:
+			a.receiveNotification(workCtx, w, r, handler, appSecret)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (a *Adapter) verifySubscription(w http.ResponseWriter, r *http.Request, verifyToken string) {
+	query := r.URL.Query()
+	token := query.Get("hub.verify_token")
+	if query.Get("hub.mode") != "subscribe" || verifyToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(verifyToken)) != 1 {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, query.Get("hub.challenge"))
+}
+
+func (a *Adapter) receiveNotification(ctx context.Context, w http.ResponseWriter, r *http.Request, handler channel.Handler, appSecret string) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !validSignature(body, r.Header.Get("X-Hub-Signature-256"), appSecret) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" || change.Value.Metadata.PhoneNumberID != a.client.PhoneNumberID() {
+				continue
+			}
+			for _, m := range change.Value.Messages {
+				msg, ok := a.toMessage(m, change.Value.Contacts)
+				if !ok {
+					log.Info().Str("type", m.Type).Msg("ignoring unsupported whatsapp cloud message type")
+					continue
+				}
+				handler.HandleMessage(ctx, a, msg)
+			}
+		}
+	}
+
+	// The Cloud API retries notifications that are not acknowledged quickly,
+	// messages are handled in the background.
+	w.WriteHeader(http.StatusOK)
+}
+
+// validSignature checks the HMAC-SHA256 of the body signed with the app
+// secret, sent as "sha256=<hex>".
+func validSignature(body []byte, signature string, appSecret string) bool {
+	if appSecret == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	gotMAC, err := hex.DecodeString(got)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+	return hmac.Equal(gotMAC, mac.Sum(nil))
+}
+
+func (a *Adapter) toMessage(m webhookMessage, contacts []webhookContact) (channel.Message, bool) {
+	msg := channel.Message{
+		ID:      m.ID,
+		Channel: ChannelName,
+		ChatID:  m.From,
+		Sender: channel.Sender{
+			ID:    m.From,
+			Phone: m.From,
+		},
+	}
+	for _, contact := range contacts {
+		if contact.WaID == m.From {
+			msg.Sender.Name = contact.Profile.Name
+		}
+	}
+	if ts, err := strconv.ParseInt(m.Timestamp, 10, 64); err == nil {
+		msg.ReceivedAt = time.Unix(ts, 0)
+	}
+
+	switch m.Type {
+	case "text":
+		if m.Text == nil {
+			return msg, false
+		}
+		msg.Text = strings.TrimSpace(m.Text.Body)
+	case "audio":
+		msg.Audio = a.attachment(m.Audio)
+	case "image":
+		msg.Image = a.attachment(m.Image)
+	case "document":
+		msg.Document = a.attachment(m.Document)
+	default:
+		return msg, false
+	}
+	return msg, true
+}
+
+func (a *Adapter) attachment(media *webhookMedia) *channel.Attachment {
+	if media == nil {
+		return nil
+	}
+	return &channel.Attachment{
+		MimeType: media.MimeType,
+		FileName: media.Filename,
+		Caption:  media.Caption,
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return a.client.DownloadMedia(ctx, media.ID)
+		},
+	}
+}
+
+func (a *Adapter) SendText(ctx context.Context, chatID string, text string) error {
+	return a.client.SendText(ctx, chatID, text)
+}
+
+func (a *Adapter) SendImage(ctx context.Context, chatID string, media channel.Media) error {
+	mediaID, err := a.upload(ctx, media)
+	if err != nil {
+		return err
+	}
+	return a.client.SendImage(ctx, chatID, mediaID, media.Caption)
+}
+
+func (a *Adapter) SendDocument(ctx context.Context, chatID string, media channel.Media) error {
+	mediaID, err := a.upload(ctx, media)
+	if err != nil {
+		return err
+	}
+
+	fileName := media.FileName
+	if fileName == "" {
+		fileName = filepath.Base(media.FilePath)
+	}
+	return a.client.SendDocument(ctx, chatID, mediaID, fileName, media.Caption)
+}
+
+func (a *Adapter) upload(ctx context.Context, media channel.Media) (string, error) {
+	mimetype := media.MimeType
+	if mimetype == "" {
+		mimetype = channel.MimeTypeFromPath(media.FilePath)
+	}
+	return a.client.UploadMedia(ctx, media.FilePath, mimetype)
+}
@@ -0,0 +1,286 @@
+package whatsappcloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/defryfazz/fazztalog/internal/channel"
+)
+
+const (
+	testToken         = "graph-token"
+	testPhoneNumberID = "1099"
+	testAppSecret     = "app-secret"
+	testVerifyToken   = "verify-me"
+)
+
+// fakeGraphAPI serves the Graph API endpoints used by the client: media upload,
+// messages, media lookup and the media download URL it returns.
+type fakeGraphAPI struct {
+	t       *testing.T
+	url     string
+	mu      sync.Mutex
+	uploads []map[string]string
+	sent    []map[string]any
+}
+
+func (f *fakeGraphAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": "Invalid OAuth access token", "code": 190}})
+		return
+	}
+
+	switch r.Method + " " + r.URL.Path {
+	case "POST /" + DefaultAPIVersion + "/" + testPhoneNumberID + "/media":
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			f.t.Errorf("parse media form: %v", err)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			f.t.Errorf("media upload without file: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		f.mu.Lock()
+		f.uploads = append(f.uploads, map[string]string{
+			"messaging_product": r.FormValue("messaging_product"),
+			"type":              r.FormValue("type"),
+			"file":              header.Filename,
+			"content_type":      header.Header.Get("Content-Type"),
+			"content":           string(content),
+		})
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(uploadResponse{ID: "media-out-1"})
+	case "POST /" + DefaultAPIVersion + "/" + testPhoneNumberID + "/messages":
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			f.t.Errorf("decode message: %v", err)
+		}
+		f.mu.Lock()
+		f.sent = append(f.sent, payload)
+		f.mu.Unlock()
+		io.WriteString(w, `{"messages":[{"id":"wamid.out"}]}`)
+	case "GET /" + DefaultAPIVersion + "/media-in-1":
+		json.NewEncoder(w).Encode(mediaInfo{ID: "media-in-1", URL: f.url + "/download/media-in-1", MimeType: "image/jpeg"})
+	case "GET /download/media-in-1":
+		io.WriteString(w, "JPEG")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": "Unknown path", "code": 100}})
+	}
+}
+
+// recordingHandler records the messages dispatched by the webhook.
+type recordingHandler struct {
+	mu      sync.Mutex
+	handled []channel.Message
+}
+
+func (h *recordingHandler) HandleMessage(ctx context.Context, messenger channel.Messenger, msg channel.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handled = append(h.handled, msg)
+}
+
+func newTestAdapter(t *testing.T) (*Adapter, *fakeGraphAPI) {
+	api := &fakeGraphAPI{t: t}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	api.url = server.URL
+
+	return NewAdapter(NewClient(ClientParams{
+		GraphURL:      server.URL,
+		AccessToken:   testToken,
+		PhoneNumberID: testPhoneNumberID,
+	})), api
+}
+
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidSignature(t *testing.T) {
+	body := []byte(`{"object":"whatsapp_business_account"}`)
+	tests := []struct {
+		name      string
+		signature string
+		secret    string
+		want      bool
+	}{
+		{"valid", sign(body, testAppSecret), testAppSecret, true},
+		{"other secret", sign(body, "other"), testAppSecret, false},
+		{"other body", sign([]byte(`{}`), testAppSecret), testAppSecret, false},
+		{"missing prefix", strings.TrimPrefix(sign(body, testAppSecret), "sha256="), testAppSecret, false},
+		{"not hex", "sha256=zz", testAppSecret, false},
+		{"empty", "", testAppSecret, false},
+		{"no app secret", sign(body, ""), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validSignature(body, tt.signature, tt.secret); got != tt.want {
+				t.Errorf("validSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookVerification(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	webhook := adapter.WebhookHandler(context.Background(), &recordingHandler{}, testVerifyToken, testAppSecret)
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+		wantBody   string
+	}{
+		{"subscribe", url.Values{"hub.mode": {"subscribe"}, "hub.verify_token": {testVerifyToken}, "hub.challenge": {"1158201444"}}, http.StatusOK, "1158201444"},
+		{"wrong token", url.Values{"hub.mode": {"subscribe"}, "hub.verify_token": {"nope"}, "hub.challenge": {"1158201444"}}, http.StatusForbidden, ""},
+		{"wrong mode", url.Values{"hub.mode": {"unsubscribe"}, "hub.verify_token": {testVerifyToken}, "hub.challenge": {"1158201444"}}, http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			webhook.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhook?"+tt.query.Encode(), nil))
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatchesMessages(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	handler := &recordingHandler{}
+	webhook := adapter.WebhookHandler(context.Background(), handler, testVerifyToken, testAppSecret)
+
+	body := []byte(`{
+		"object": "whatsapp_business_account",
+		"entry": [{
+			"id": "entry-1",
+			"changes": [{
+				"field": "messages",
+				"value": {
+					"messaging_product": "whatsapp",
+					"metadata": {"display_phone_number": "6281100", "phone_number_id": "1099"},
+					"contacts": [{"wa_id": "628123456789", "profile": {"name": "Budi"}}],
+					"messages": [
+						{"id": "wamid.1", "from": "628123456789", "timestamp": "1700000000", "type": "text", "text": {"body": " buatkan brosur kopi "}},
+						{"id": "wamid.2", "from": "628123456789", "timestamp": "1700000001", "type": "image", "image": {"id": "media-in-1", "mime_type": "image/jpeg", "caption": "Kopi Susu"}},
+						{"id": "wamid.3", "from": "628123456789", "timestamp": "1700000002", "type": "sticker"}
+					]
+				}
+			}, {
+				"field": "messages",
+				"value": {
+					"metadata": {"phone_number_id": "2000"},
+					"messages": [{"id": "wamid.4", "from": "628999", "type": "text", "text": {"body": "other number"}}]
+				}
+			}]
+		}]
+	}`)
+
+	unsigned := httptest.NewRecorder()
+	webhook.ServeHTTP(unsigned, httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body)))
+	if unsigned.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned notification got %d, want %d", unsigned.Code, http.StatusUnauthorized)
+	}
+	if len(handler.handled) != 0 {
+		t.Fatalf("unsigned notification dispatched %d messages", len(handler.handled))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign(body, testAppSecret))
+	rec := httptest.NewRecorder()
+	webhook.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("signed notification got %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if len(handler.handled) != 2 {
+		t.Fatalf("handled %d messages, want 2 (stickers and other numbers are ignored)", len(handler.handled))
+	}
+	text := handler.handled[0]
+	if text.ID != "wamid.1" || text.Channel != ChannelName || text.ChatID != "628123456789" || text.Text != "buatkan brosur kopi" {
+		t.Errorf("unexpected text message %+v", text)
+	}
+	if text.Sender.ID != "628123456789" || text.Sender.Phone != "628123456789" || text.Sender.Name != "Budi" || text.ReceivedAt.Unix() != 1700000000 {
+		t.Errorf("unexpected sender %+v received at %v", text.Sender, text.ReceivedAt)
+	}
+
+	image := handler.handled[1]
+	if image.ID != "wamid.2" || image.Image == nil || image.Image.MimeType != "image/jpeg" || image.Image.Caption != "Kopi Susu" {
+		t.Fatalf("unexpected image message %+v", image)
+	}
+	r, err := image.Image.Open(context.Background())
+	if err != nil {
+		t.Fatalf("open image attachment: %v", err)
+	}
+	defer r.Close()
+	if content, _ := io.ReadAll(r); string(content) != "JPEG" {
+		t.Errorf("downloaded %q, want JPEG", content)
+	}
+}
+
+func TestAdapterSendImage(t *testing.T) {
+	adapter, api := newTestAdapter(t)
+	brochure := filepath.Join(t.TempDir(), "brochure.png")
+	if err := os.WriteFile(brochure, []byte("\x89PNG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := adapter.SendImage(ctx, "628123456789", channel.Media{FilePath: brochure, Caption: "Brosur kopi"}); err != nil {
+		t.Fatalf("SendImage: %v", err)
+	}
+
+	if len(api.uploads) != 1 {
+		t.Fatalf("uploaded %d files, want 1", len(api.uploads))
+	}
+	upload := api.uploads[0]
+	if upload["messaging_product"] != "whatsapp" || upload["type"] != "image/png" || upload["file"] != "brochure.png" ||
+		upload["content_type"] != "image/png" || upload["content"] != "\x89PNG" {
+		t.Errorf("unexpected upload %v", upload)
+	}
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	sent := api.sent[0]
+	image, _ := sent["image"].(map[string]any)
+	if sent["to"] != "628123456789" || sent["type"] != "image" || image["id"] != "media-out-1" || image["caption"] != "Brosur kopi" {
+		t.Errorf("unexpected image message %v", sent)
+	}
+}
+
+func TestClientReportsGraphErrors(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+
+	_, err := adapter.client.DownloadMedia(context.Background(), "missing")
+	if err == nil || !strings.Contains(err.Error(), "graph error 100: Unknown path") {
+		t.Errorf("DownloadMedia of an unknown media returned %v", err)
+	}
+
+	unauthorized := NewClient(ClientParams{GraphURL: adapter.client.graphURL, AccessToken: "wrong", PhoneNumberID: testPhoneNumberID})
+	err = unauthorized.SendText(context.Background(), "628123456789", "halo")
+	if err == nil || !strings.Contains(err.Error(), "graph error 190") {
+		t.Errorf("SendText with a wrong token returned %v", err)
+	}
+}
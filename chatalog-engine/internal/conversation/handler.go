@@ -0,0 +1,504 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"sync"
+
+	"github.com/defryfazz/fazztalog/internal/ai"
+	"github.com/defryfazz/fazztalog/internal/brochure"
+	"github.com/defryfazz/fazztalog/internal/channel"
+	"github.com/defryfazz/fazztalog/internal/device"
+	"github.com/defryfazz/fazztalog/internal/job"
+	"github.com/defryfazz/fazztalog/internal/logger"
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/defryfazz/fazztalog/internal/message"
+	"github.com/defryfazz/fazztalog/internal/metrics"
+	"github.com/defryfazz/fazztalog/internal/sheet"
+	"github.com/defryfazz/fazztalog/internal/storage"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var errSenderNotAuthenticated = fmt.Errorf("sender not authenticated")
+
+// Handler holds the brochure conversation flow. It does not know which channel
+// a message came from; replies go through the channel.Messenger of the message.
+type Handler struct {
+	aiEngine        ai.Engine
+	merchantService merchant.Service
+	messageService  message.Service
+	jobService      job.Service
+	deviceService   device.Service
+	brochureService brochure.Service
+	sheetService    sheet.Service
+	storage         storage.Storage
+	tempDir         string
+
+	mu         sync.Mutex
+	closing    bool
+	inflight   sync.WaitGroup
+	messengers map[string]channel.Messenger
+}
+
+type HandlerParams struct {
+	AIEngine        ai.Engine
+	MerchantService merchant.Service
+	MessageService  message.Service
+	JobService      job.Service
+	DeviceService   device.Service
+	BrochureService brochure.Service
+	SheetService    sheet.Service
+	Storage         storage.Storage
+	TempDirectory   string
+}
+
+func NewHandler(params HandlerParams) *Handler {
+	return &Handler{
+		aiEngine:        params.AIEngine,
+		merchantService: params.MerchantService,
+		messageService:  params.MessageService,
+		jobService:      params.JobService,
+		deviceService:   params.DeviceService,
+		brochureService: params.BrochureService,
+		sheetService:    params.SheetService,
+		storage:         params.Storage,
+		tempDir:         params.TempDirectory,
+		messengers:      map[string]channel.Messenger{},
+	}
+}
+
+// RegisterMessenger makes a channel account available to ResumeJobs.
+func (h *Handler) RegisterMessenger(messenger channel.Messenger) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messengers[messengerKey(messenger.Name(), messenger.AccountID())] = messenger
+}
+
+// UnregisterMessenger removes a channel account, e.g. a WhatsApp number that
+// was logged out.
+func (h *Handler) UnregisterMessenger(messenger channel.Messenger) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := messengerKey(messenger.Name(), messenger.AccountID())
+	if h.messengers[key] == messenger {
+		delete(h.messengers, key)
+	}
+}
+
+// messenger returns the messenger of the channel account. Jobs created before
+// accounts were tracked have no account and go to any account of the channel.
+func (h *Handler) messenger(channelName string, accountID string) (channel.Messenger, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if messenger, ok := h.messengers[messengerKey(channelName, accountID)]; ok {
+		return messenger, true
+	}
+	if accountID != "" {
+		return nil, false
+	}
+	for _, messenger := range h.messengers {
+		if messenger.Name() == channelName {
+			return messenger, true
+		}
+	}
+	return nil, false
+}
+
+func messengerKey(channelName string, accountID string) string {
+	return channelName + ":" + accountID
+}
+
+// HandleMessage processes msg in the background so the channel's receive loop
+// is not blocked by long running generations.
+func (h *Handler) HandleMessage(ctx context.Context, messenger channel.Messenger, msg channel.Message) {
+	metrics.MessagesReceived.WithLabelValues(msg.Channel).Inc()
+	ctx = logger.WithCorrelationID(ctx, uuid.New().String())
+	ctx = zerolog.Ctx(ctx).With().
+		Str("channel", msg.Channel).
+		Str("account_id", msg.AccountID).
+		Str("message_id", msg.ID).
+		Logger().WithContext(ctx)
+	h.dispatch(func() {
+		h.handleMessage(ctx, messenger, msg)
+	})
+}
+
+// dispatch runs fn in its own goroutine and tracks it as in-flight work, so
+// Shutdown can wait for it. Work is dropped once the handler is shutting down.
+func (h *Handler) dispatch(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return
+	}
+
+	h.inflight.Add(1)
+	metrics.InFlightMessages.Inc()
+	go func() {
+		defer h.inflight.Done()
+		defer metrics.InFlightMessages.Dec()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().Interface("panic", r).Msg("recovered from panic")
+			}
+		}()
+		fn()
+	}()
+}
+
+// Shutdown stops accepting new messages and waits for in-flight work to finish.
+// It returns the context error if the work did not finish before ctx is done,
+// in which case the caller should cancel the work context so unfinished jobs
+// are left pending and resumed by ResumeJobs on the next start.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until all in-flight work has returned.
+func (h *Handler) Wait() {
+	h.inflight.Wait()
+}
+
+// ResumeJobs continues brochure jobs that were interrupted by a previous
+// shutdown before their brochure was sent. Jobs of channel accounts that have
+// no registered messenger are left for the process serving that account.
+func (h *Handler) ResumeJobs(ctx context.Context) error {
+	jobs, err := h.jobService.GetUnfinishedJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, brochureJob := range jobs {
+		messenger, ok := h.messenger(brochureJob.Channel, brochureJob.AccountID)
+		if !ok {
+			continue
+		}
+
+		jobCtx := logger.WithCorrelationID(ctx, uuid.New().String())
+		jobCtx = zerolog.Ctx(jobCtx).With().Str("job_id", brochureJob.ID).Logger().WithContext(jobCtx)
+		zerolog.Ctx(jobCtx).Info().Msg("resuming brochure job")
+		h.dispatch(func() {
+			h.runBrochureJob(jobCtx, messenger, &brochureJob)
+		})
+	}
+	return nil
+}
+
+func (h *Handler) handleMessage(ctx context.Context, messenger channel.Messenger, msg channel.Message) {
+	if msg.Sender.Phone == "" {
+		phone, err := h.resolveSenderPhone(ctx, msg)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("sender_id", msg.Sender.ID).Msg("error resolving sender")
+			return
+		}
+		msg.Sender.Phone = phone
+	}
+
+	err := h.authenticateSender(msg)
+	if err != nil {
+		return
+	}
+
+	if msg.AccountID != "" {
+		serves, err := h.deviceService.Serves(ctx, msg.AccountID, msg.Sender.Phone)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("error checking number routing")
+			return
+		}
+		if !serves {
+			zerolog.Ctx(ctx).Info().Str("merchant_phone", msg.Sender.Phone).Msg("number does not serve merchant, ignoring message")
+			return
+		}
+	}
+
+	// The message is only marked as processed once its work is recorded, so a
+	// message redelivered after a crash during transcription or intent
+	// detection is handled again instead of being lost.
+	processed, err := h.messageService.IsProcessed(ctx, msg.ID, msg.ChatID)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error checking processed message")
+		return
+	}
+	if processed {
+		zerolog.Ctx(ctx).Info().Str("sender_id", msg.Sender.ID).Msg("skipping duplicate message")
+		return
+	}
+
+	textMessage := ""
+	switch {
+	case msg.Text != "":
+		textMessage = msg.Text
+	case msg.Audio != nil:
+		textMessage, err = h.transcribeAudio(ctx, msg.Audio)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("error transcribing audio")
+			return
+		}
+	case msg.Image != nil:
+		textMessage = msg.Image.Caption
+	}
+
+	if textMessage == "" {
+		return
+	}
+
+	intent, err := h.aiEngine.DetermineIntent(ctx, textMessage)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error determining intent")
+		return
+	}
+	intentLabel := string(ai.IntentUnknown)
+	switch ai.Intent(intent.Intent) {
+	case ai.IntentBrochureGeneration, ai.IntentBrochureResend, ai.IntentBrochureList, ai.IntentSheetSync:
+		intentLabel = intent.Intent
+	}
+	metrics.IntentsDetected.WithLabelValues(intentLabel).Inc()
+
+	if ai.Intent(intent.Intent) == ai.IntentBrochureGeneration {
+		h.generateBrochure(ctx, messenger, msg, intent.Products)
+		return
+	}
+	if !h.markProcessed(ctx, msg) {
+		return
+	}
+	switch ai.Intent(intent.Intent) {
+	case ai.IntentBrochureResend:
+		h.resendLatestBrochure(ctx, messenger, msg)
+	case ai.IntentBrochureList:
+		h.listBrochures(ctx, messenger, msg, ai.Period(intent.Period))
+	case ai.IntentSheetSync:
+		h.syncSheet(ctx, messenger, msg)
+	default:
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry, I can't help you with that. I can only generate brochures, send your last brochure again, list your brochures or sync your sheet.")
+	}
+}
+
+func (h *Handler) generateBrochure(ctx context.Context, messenger channel.Messenger, msg channel.Message, productNames []string) {
+	brochureJob, created, err := h.jobService.CreateBrochureJob(ctx, job.CreateBrochureJobParams{
+		IdempotencyKey: fmt.Sprintf("%s:%s:%s", msg.Channel, msg.ChatID, msg.ID),
+		MerchantPhone:  msg.Sender.Phone,
+		Channel:        msg.Channel,
+		AccountID:      msg.AccountID,
+		ChatID:         msg.ChatID,
+		ProductNames:   productNames,
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error creating brochure job")
+		return
+	}
+	h.markProcessed(ctx, msg)
+	if !created {
+		// Another delivery of the message created the job. It is either still
+		// running or left unfinished for ResumeJobs.
+		zerolog.Ctx(ctx).Info().Str("job_id", brochureJob.ID).Msg("skipping duplicate message, brochure job already exists")
+		return
+	}
+	h.runBrochureJob(ctx, messenger, brochureJob)
+}
+
+// markProcessed records msg as processed. It returns false when another
+// delivery of msg has been processed already or the record failed.
+func (h *Handler) markProcessed(ctx context.Context, msg channel.Message) bool {
+	firstSeen, err := h.messageService.MarkProcessed(ctx, msg.ID, msg.ChatID, msg.Sender.ID)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error marking message as processed")
+		return false
+	}
+	if !firstSeen {
+		zerolog.Ctx(ctx).Info().Str("sender_id", msg.Sender.ID).Msg("skipping duplicate message")
+	}
+	return firstSeen
+}
+
+func (h *Handler) transcribeAudio(ctx context.Context, audio *channel.Attachment) (string, error) {
+	if err := os.MkdirAll(fmt.Sprintf("%s/transcriptions", h.tempDir), 0755); err != nil {
+		return "", fmt.Errorf("error creating directory: %w", err)
+	}
+
+	src, err := audio.Open(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error downloading audio: %w", err)
+	}
+	defer src.Close()
+
+	audioFileName := fmt.Sprintf("%s/transcriptions/%s%s", h.tempDir, uuid.New().String(), audioExtension(audio.MimeType))
+	f, err := os.Create(audioFileName)
+	if err != nil {
+		return "", fmt.Errorf("error creating audio file: %w", err)
+	}
+	defer os.Remove(audioFileName)
+	defer f.Close()
+
+	if _, err := io.Copy(f, src); err != nil {
+		return "", fmt.Errorf("error downloading audio: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("error opening audio: %w", err)
+	}
+
+	return h.aiEngine.TranscribeAudio(ctx, f)
+}
+
+// runBrochureJob generates and sends the brochure of the given job. Steps that
+// were already completed by a previous attempt of the same job are skipped, so
+// a retried job never sends the brochure twice.
+func (h *Handler) runBrochureJob(ctx context.Context, messenger channel.Messenger, brochureJob *job.Job) {
+	if brochureJob.Status == job.StatusSent {
+		zerolog.Ctx(ctx).Info().Str("job_id", brochureJob.ID).Msg("brochure job has already been sent, skipping")
+		return
+	}
+
+	chatID := brochureJob.ChatID
+	filePath := brochureJob.FilePath
+	if brochureJob.Status != job.StatusGenerated || !h.brochureStored(ctx, filePath) {
+		h.sendText(ctx, messenger, chatID, "`Generating brochure...`")
+		generated, err := h.merchantService.GenerateBrochure(ctx, merchant.BrochureRequest{
+			MerchantPhone: brochureJob.MerchantPhone,
+			JobID:         brochureJob.ID,
+			ProductNames:  brochureJob.ProductNames,
+			Style:         brochureJob.Options.Style,
+			Format:        brochureJob.Options.Format,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Str("job_id", brochureJob.ID).Msg("brochure job interrupted, leaving it for resume")
+				return
+			}
+			zerolog.Ctx(ctx).Error().Err(err).Str("job_id", brochureJob.ID).Msg("error generating brochure")
+			h.markJobFailed(ctx, brochureJob, err)
+			h.sendText(ctx, messenger, chatID, "Sorry the brochure generation failed. Please try again later.")
+			return
+		}
+		filePath = generated.FilePath
+		// Status writes must land even when shutdown cancels ctx right after
+		// the step, or the job is resumed and the step done again.
+		if err := h.jobService.MarkGenerated(context.WithoutCancel(ctx), brochureJob.ID, filePath); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("job_id", brochureJob.ID).Msg("error marking brochure job as generated")
+		}
+	}
+
+	h.sendText(ctx, messenger, chatID, "`Uploading brochure...`")
+	err := h.sendBrochure(ctx, messenger, chatID, filePath)
+	if err != nil {
+		if ctx.Err() != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("job_id", brochureJob.ID).Msg("brochure job interrupted, leaving it for resume")
+			return
+		}
+		zerolog.Ctx(ctx).Error().Err(err).Str("job_id", brochureJob.ID).Msg("error sending brochure image")
+		h.markJobFailed(ctx, brochureJob, err)
+		h.sendText(ctx, messenger, chatID, "Sorry the brochure sending failed. Please try again later.")
+		return
+	}
+
+	if err := h.jobService.MarkSent(context.WithoutCancel(ctx), brochureJob.ID); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("job_id", brochureJob.ID).Msg("error marking brochure job as sent")
+	}
+}
+
+func (h *Handler) markJobFailed(ctx context.Context, brochureJob *job.Job, cause error) {
+	if err := h.jobService.MarkFailed(context.WithoutCancel(ctx), brochureJob.ID, cause); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("job_id", brochureJob.ID).Msg("error marking brochure job as failed")
+	}
+}
+
+func (h *Handler) sendText(ctx context.Context, messenger channel.Messenger, chatID string, text string) {
+	if err := messenger.SendText(ctx, chatID, text); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("chat_id", chatID).Msg("error sending message")
+	}
+}
+
+// resolveSenderPhone looks up the phone of the merchant linked to the sender
+// for channels that do not expose phone numbers.
+func (h *Handler) resolveSenderPhone(ctx context.Context, msg channel.Message) (string, error) {
+	m, err := h.merchantService.GetMerchantByAccount(ctx, msg.Channel, msg.Sender.ID)
+	if err != nil {
+		return "", err
+	}
+	if m == nil {
+		return "", nil
+	}
+	return m.Phone, nil
+}
+
+func (h *Handler) authenticateSender(msg channel.Message) error {
+	if msg.IsGroup {
+		return errSenderNotAuthenticated
+	}
+
+	whitelistedPhones := []string{
+		"6282123430340", // Defry
+		"6285224416325", // Hery
+		"6282148924797", // Farrel
+		"6281575749888", // Nurwanto
+		"6281287456169", // Alex
+	}
+
+	if msg.Sender.Phone == "" {
+		return fmt.Errorf("failed to get phone of sender: %s", msg.Sender.ID)
+	}
+	for _, whitelistedPhone := range whitelistedPhones {
+		if msg.Sender.Phone == whitelistedPhone {
+			return nil
+		}
+	}
+
+	return errSenderNotAuthenticated
+}
+
+// brochureStored reports whether the brochure with the storage key is still
+// stored, e.g. it was not removed because of its retention.
+func (h *Handler) brochureStored(ctx context.Context, key string) bool {
+	exists, err := storage.Exists(ctx, h.storage, key)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("key", key).Msg("error checking brochure in storage")
+	}
+	return exists
+}
+
+// sendBrochure sends the brochure with the storage key as an image.
+func (h *Handler) sendBrochure(ctx context.Context, messenger channel.Messenger, chatID string, key string) error {
+	filePath, cleanup, err := storage.LocalFile(ctx, h.storage, key, h.tempDir)
+	if err != nil {
+		return fmt.Errorf("error getting brochure from storage: %w", err)
+	}
+	defer cleanup()
+	return messenger.SendImage(ctx, chatID, channel.Media{FilePath: filePath})
+}
+
+// audioExtension returns the file extension for the audio mimetype, which the
+// transcription API uses to detect the audio format.
+func audioExtension(mimetype string) string {
+	mediaType, _, _ := mime.ParseMediaType(mimetype)
+	switch mediaType {
+	case "audio/ogg", "audio/opus":
+		return ".ogg"
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/mp4", "audio/m4a", "audio/x-m4a", "audio/aac":
+		return ".m4a"
+	case "audio/webm":
+		return ".webm"
+	default:
+		return ".wav"
+	}
+}
@@ -0,0 +1,131 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/ai"
+	"github.com/defryfazz/fazztalog/internal/brochure"
+	"github.com/defryfazz/fazztalog/internal/channel"
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/rs/zerolog"
+)
+
+// brochureListLimit caps the brochures listed in one reply to keep it readable
+// in a chat.
+const brochureListLimit = 10
+
+// resendLatestBrochure sends the merchant's most recent brochure again without
+// generating a new one.
+func (h *Handler) resendLatestBrochure(ctx context.Context, messenger channel.Messenger, msg channel.Message) {
+	m, ok := h.senderMerchant(ctx, messenger, msg)
+	if !ok {
+		return
+	}
+
+	latest, err := h.brochureService.GetLatestBrochure(ctx, m.ID)
+	if err != nil {
+		if errors.Is(err, brochure.ErrBrochureNotFound) {
+			h.sendText(ctx, messenger, msg.ChatID, "You don't have any brochures yet. Ask me to create one for your products.")
+			return
+		}
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error getting latest brochure")
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry, I couldn't find your last brochure. Please try again later.")
+		return
+	}
+	if !h.brochureStored(ctx, latest.FilePath) {
+		zerolog.Ctx(ctx).Warn().Str("brochure_id", latest.ID).Msg("brochure file is missing")
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry, your last brochure is no longer available. Please ask me to create a new one.")
+		return
+	}
+
+	h.sendText(ctx, messenger, msg.ChatID, "`Uploading brochure...`")
+	if err := h.sendBrochure(ctx, messenger, msg.ChatID, latest.FilePath); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("brochure_id", latest.ID).Msg("error resending brochure")
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry the brochure sending failed. Please try again later.")
+	}
+}
+
+// listBrochures replies with the merchant's brochures of the given period,
+// newest first.
+func (h *Handler) listBrochures(ctx context.Context, messenger channel.Messenger, msg channel.Message, period ai.Period) {
+	m, ok := h.senderMerchant(ctx, messenger, msg)
+	if !ok {
+		return
+	}
+
+	brochures, err := h.brochureService.ListBrochures(ctx, m.ID, periodStart(period, time.Now()), brochureListLimit)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error listing brochures")
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry, I couldn't list your brochures. Please try again later.")
+		return
+	}
+	if len(brochures) == 0 {
+		h.sendText(ctx, messenger, msg.ChatID, fmt.Sprintf("You don't have any brochures %s.", periodLabel(period)))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Your brochures %s:\n", periodLabel(period))
+	for i, item := range brochures {
+		names := make([]string, 0, len(item.Products))
+		for _, p := range item.Products {
+			names = append(names, p.Name)
+		}
+		fmt.Fprintf(&b, "%d. %s - %s\n", i+1, item.CreatedAt.Format("Mon 2 Jan 15:04"), strings.Join(names, ", "))
+	}
+	if len(brochures) == brochureListLimit {
+		fmt.Fprintf(&b, "Only the latest %d brochures are shown.\n", brochureListLimit)
+	}
+	b.WriteString(`Say "send my last brochure" to get the latest one again.`)
+	h.sendText(ctx, messenger, msg.ChatID, b.String())
+}
+
+// senderMerchant returns the merchant of the sender, replying to the sender
+// when there is none.
+func (h *Handler) senderMerchant(ctx context.Context, messenger channel.Messenger, msg channel.Message) (*merchant.Merchant, bool) {
+	m, err := h.merchantService.GetMerchantByPhone(ctx, msg.Sender.Phone)
+	if err != nil {
+		if errors.Is(err, merchant.ErrMerchantNotFound) {
+			h.sendText(ctx, messenger, msg.ChatID, "Sorry, your number is not registered as a merchant.")
+			return nil, false
+		}
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error getting merchant")
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry, something went wrong. Please try again later.")
+		return nil, false
+	}
+	return m, true
+}
+
+// periodStart returns the start of the period containing now. Brochures of
+// every period are listed for an unknown period.
+func periodStart(period ai.Period, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case ai.PeriodToday:
+		return today
+	case ai.PeriodThisWeek:
+		// Weeks start on Monday.
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	case ai.PeriodThisMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	default:
+		return time.Time{}
+	}
+}
+
+func periodLabel(period ai.Period) string {
+	switch period {
+	case ai.PeriodToday:
+		return "today"
+	case ai.PeriodThisWeek:
+		return "this week"
+	case ai.PeriodThisMonth:
+		return "this month"
+	default:
+		return "so far"
+	}
+}
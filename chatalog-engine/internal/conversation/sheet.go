@@ -0,0 +1,163 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/defryfazz/fazztalog/internal/channel"
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/defryfazz/fazztalog/internal/sheet"
+	"github.com/rs/zerolog"
+)
+
+// syncSummaryItems caps the items listed per kind of change in a sync
+// message to keep it readable in a chat.
+const syncSummaryItems = 10
+
+var errNoMessenger = errors.New("no messenger can reach the merchant")
+
+// syncSheet updates the merchant's catalog from the sheet and replies with
+// what changed.
+func (h *Handler) syncSheet(ctx context.Context, messenger channel.Messenger, msg channel.Message) {
+	m, ok := h.senderMerchant(ctx, messenger, msg)
+	if !ok {
+		return
+	}
+
+	h.sendText(ctx, messenger, msg.ChatID, "`Syncing your sheet...`")
+	sync, err := h.sheetService.SyncMerchant(ctx, *m)
+	switch {
+	case errors.Is(err, sheet.ErrNotConfigured):
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry, syncing from a sheet is not available yet.")
+		return
+	case errors.Is(err, sheet.ErrNoRows) && len(sync.Skipped) == 0:
+		h.sendText(ctx, messenger, msg.ChatID, fmt.Sprintf("I couldn't find any products for your number in the sheet. Make sure the user_id column of your rows is %s.", m.Phone))
+		return
+	case err != nil && !errors.Is(err, sheet.ErrNoRows):
+		zerolog.Ctx(ctx).Error().Err(err).Str("merchant_id", m.ID).Msg("error syncing sheet")
+		h.sendText(ctx, messenger, msg.ChatID, "Sorry, I couldn't sync your sheet. Please try again later.")
+		return
+	}
+
+	h.sendText(ctx, messenger, msg.ChatID, syncSummary(sync))
+}
+
+// NotifySheetSync tells the merchant what a scheduled sheet sync changed. It
+// is sent from a WhatsApp number that serves the merchant.
+func (h *Handler) NotifySheetSync(ctx context.Context, sync sheet.MerchantSync) error {
+	messenger, err := h.merchantMessenger(ctx, sync.Merchant.Phone)
+	if err != nil {
+		return err
+	}
+	text := "Your sheet was synced automatically.\n" + syncSummary(&sync)
+	return messenger.SendText(ctx, messenger.PhoneChatID(sync.Merchant.Phone), text)
+}
+
+// merchantMessenger returns a registered messenger that can message the
+// merchant with the given phone first.
+func (h *Handler) merchantMessenger(ctx context.Context, merchantPhone string) (channel.PhoneMessenger, error) {
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.messengers))
+	for key := range h.messengers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var candidates []channel.PhoneMessenger
+	for _, key := range keys {
+		if messenger, ok := h.messengers[key].(channel.PhoneMessenger); ok {
+			candidates = append(candidates, messenger)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, messenger := range candidates {
+		if messenger.AccountID() == "" {
+			return messenger, nil
+		}
+		serves, err := h.deviceService.Serves(ctx, messenger.AccountID(), merchantPhone)
+		if err != nil {
+			return nil, err
+		}
+		if serves {
+			return messenger, nil
+		}
+	}
+	return nil, errNoMessenger
+}
+
+// syncSummary describes the outcome of a sync in a chat message.
+func syncSummary(sync *sheet.MerchantSync) string {
+	var b strings.Builder
+	res := sync.Result
+	switch {
+	case res == nil:
+		b.WriteString("None of your rows in the sheet could be imported, so your catalog was not changed.\n")
+	case len(res.Added) == 0 && len(res.Updated) == 0 && len(res.Removed) == 0:
+		fmt.Fprintf(&b, "Your catalog is already up to date with your sheet (%d products).\n", res.Unchanged)
+	default:
+		fmt.Fprintf(&b, "Your catalog is synced with your sheet: %d added, %d updated, %d removed, %d unchanged.\n",
+			len(res.Added), len(res.Updated), len(res.Removed), res.Unchanged)
+	}
+
+	if res != nil {
+		var increases, decreases, renames []string
+		for _, change := range res.Updated {
+			before, after := change.Before, change.After
+			switch {
+			case after.Price > before.Price:
+				increases = append(increases, fmt.Sprintf("%s: Rp %.0f → Rp %.0f", after.Name, before.Price, after.Price))
+			case after.Price < before.Price:
+				decreases = append(decreases, fmt.Sprintf("%s: Rp %.0f → Rp %.0f", after.Name, before.Price, after.Price))
+			default:
+				renames = append(renames, fmt.Sprintf("%s → %s", before.Name, after.Name))
+			}
+		}
+		writeSummarySection(&b, "New items", productLines(res.Added, true))
+		writeSummarySection(&b, "Price increases", increases)
+		writeSummarySection(&b, "Price decreases", decreases)
+		writeSummarySection(&b, "Renamed", renames)
+		writeSummarySection(&b, "Removed", productLines(res.Removed, false))
+		writeSummarySection(&b, "Kept because their row could not be read", productLines(res.Kept, false))
+	}
+
+	skipped := make([]string, 0, len(sync.Skipped))
+	for _, row := range sync.Skipped {
+		line := fmt.Sprintf("row %d", row.Row)
+		if row.ID != "" {
+			line += fmt.Sprintf(" (id %s)", row.ID)
+		}
+		skipped = append(skipped, line+": "+row.Reason)
+	}
+	writeSummarySection(&b, "Rows that could not be read, please fix them in the sheet", skipped)
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func productLines(products []merchant.Product, withPrice bool) []string {
+	lines := make([]string, 0, len(products))
+	for _, p := range products {
+		if withPrice {
+			lines = append(lines, fmt.Sprintf("%s (Rp %.0f)", p.Name, p.Price))
+		} else {
+			lines = append(lines, p.Name)
+		}
+	}
+	return lines
+}
+
+func writeSummarySection(b *strings.Builder, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for i, line := range lines {
+		if i == syncSummaryItems {
+			fmt.Fprintf(b, "• and %d more\n", len(lines)-syncSummaryItems)
+			break
+		}
+		fmt.Fprintf(b, "• %s\n", line)
+	}
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// OpenSQLite opens the SQLite database at sqlitePath and creates the tables
+// used by the engine.
+func OpenSQLite(sqlitePath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CreateTables(db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// CreateTables creates the tables used by the engine in the SQLite database.
+func CreateTables(db *sql.DB) error {
+	merchantTable := `CREATE TABLE IF NOT EXISTS merchants (
+		id TEXT PRIMARY KEY,
+		name TEXT,
+		phone TEXT
+	);`
+
+	// Product IDs may come from clients, so they are only unique per merchant.
+	productTable := `CREATE TABLE IF NOT EXISTS products (
+		id TEXT,
+		merchant_id TEXT,
+		name TEXT,
+		price REAL,
+		PRIMARY KEY (merchant_id, id),
+		FOREIGN KEY (merchant_id) REFERENCES merchants(id)
+	);`
+
+	merchantAccountTable := `CREATE TABLE IF NOT EXISTS merchant_accounts (
+		channel TEXT,
+		external_id TEXT,
+		merchant_id TEXT,
+		PRIMARY KEY (channel, external_id),
+		FOREIGN KEY (merchant_id) REFERENCES merchants(id)
+	);`
+
+	processedMessageTable := `CREATE TABLE IF NOT EXISTS processed_messages (
+		id TEXT,
+		chat_id TEXT,
+		sender_id TEXT,
+		processed_at DATETIME,
+		PRIMARY KEY (id, chat_id)
+	);`
+
+	brochureJobTable := `CREATE TABLE IF NOT EXISTS brochure_jobs (
+		id TEXT PRIMARY KEY,
+		idempotency_key TEXT UNIQUE,
+		merchant_phone TEXT,
+		channel TEXT,
+		chat_id TEXT,
+		product_names TEXT,
+		options TEXT,
+		status TEXT,
+		file_path TEXT,
+		error TEXT,
+		created_at DATETIME,
+		updated_at DATETIME
+	);`
+
+	whatsappDeviceTable := `CREATE TABLE IF NOT EXISTS whatsapp_devices (
+		phone TEXT PRIMARY KEY,
+		region TEXT,
+		created_at DATETIME,
+		updated_at DATETIME
+	);`
+
+	whatsappDeviceMerchantTable := `CREATE TABLE IF NOT EXISTS whatsapp_device_merchants (
+		phone TEXT,
+		merchant_id TEXT,
+		PRIMARY KEY (phone, merchant_id),
+		FOREIGN KEY (phone) REFERENCES whatsapp_devices(phone),
+		FOREIGN KEY (merchant_id) REFERENCES merchants(id)
+	);`
+
+	brochureTable := `CREATE TABLE IF NOT EXISTS brochures (
+		id TEXT PRIMARY KEY,
+		merchant_id TEXT,
+		job_id TEXT,
+		products TEXT,
+		style TEXT,
+		format TEXT,
+		prompt TEXT,
+		engine TEXT,
+		file_path TEXT,
+		created_at DATETIME,
+		FOREIGN KEY (merchant_id) REFERENCES merchants(id)
+	);`
+
+	brochureMerchantIndex := `CREATE INDEX IF NOT EXISTS brochures_merchant_id_created_at
+		ON brochures (merchant_id, created_at);`
+
+	for _, table := range []string{merchantTable, productTable, merchantAccountTable, processedMessageTable, brochureJobTable, whatsappDeviceTable, whatsappDeviceMerchantTable, brochureTable, brochureMerchantIndex} {
+		if _, err := db.Exec(table); err != nil {
+			return err
+		}
+	}
+
+	// Columns added after a table was first released.
+	if err := addColumnIfNotExists(db, "brochure_jobs", "channel", "TEXT NOT NULL DEFAULT 'whatsapp'"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "brochure_jobs", "options", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "brochure_jobs", "account_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "merchants", "region", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "merchants", "logo_key", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "products", "photo_key", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "merchants", "sheet_url", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfNotExists(db, "products", "source_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return migrateProductsPrimaryKey(db)
+}
+
+// migrateProductsPrimaryKey rebuilds a products table created with a global
+// id primary key so its key is (merchant_id, id). SQLite cannot change the
+// primary key of a table in place.
+func migrateProductsPrimaryKey(db *sql.DB) error {
+	var merchantKey int
+	err := db.QueryRow(`SELECT pk FROM pragma_table_info('products') WHERE name = 'merchant_id'`).Scan(&merchantKey)
+	if err != nil {
+		return err
+	}
+	if merchantKey > 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`CREATE TABLE products_new (
+			id TEXT,
+			merchant_id TEXT,
+			name TEXT,
+			price REAL,
+			photo_key TEXT NOT NULL DEFAULT '',
+			source_id TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (merchant_id, id),
+			FOREIGN KEY (merchant_id) REFERENCES merchants(id)
+		);`,
+		`INSERT INTO products_new (id, merchant_id, name, price, photo_key, source_id)
+			SELECT id, merchant_id, name, price, photo_key, source_id FROM products;`,
+		`DROP TABLE products;`,
+		`ALTER TABLE products_new RENAME TO products;`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("error migrating products primary key: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name         string
+			columnType   string
+			notNull      int
+			defaultValue sql.NullString
+			primaryKey   int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
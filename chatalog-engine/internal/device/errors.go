@@ -0,0 +1,5 @@
+package device
+
+import "errors"
+
+var ErrDeviceNotFound = errors.New("device not found")
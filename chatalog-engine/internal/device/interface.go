@@ -0,0 +1,21 @@
+package device
+
+import "context"
+
+type Service interface {
+	ListDevices(ctx context.Context) ([]Device, error)
+	GetDevice(ctx context.Context, phone string) (*Device, error)
+	// SetRouting replaces the merchants and region served by the number.
+	SetRouting(ctx context.Context, phone string, params RoutingParams) (*Device, error)
+	DeleteDevice(ctx context.Context, phone string) error
+	// Serves reports whether the number may serve the merchant with the given
+	// phone. Numbers without routing serve every merchant.
+	Serves(ctx context.Context, phone string, merchantPhone string) (bool, error)
+}
+
+type Repository interface {
+	ListDevices(ctx context.Context) ([]Device, error)
+	GetDeviceByPhone(ctx context.Context, phone string) (*Device, error)
+	UpsertDevice(ctx context.Context, d Device) error
+	DeleteDevice(ctx context.Context, phone string) error
+}
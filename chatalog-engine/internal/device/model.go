@@ -0,0 +1,18 @@
+package device
+
+import "time"
+
+// Device is a WhatsApp number served by the engine and the merchants it may
+// serve. A device without merchants and region serves every merchant.
+type Device struct {
+	Phone       string
+	Region      string
+	MerchantIDs []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type RoutingParams struct {
+	Region      string
+	MerchantIDs []string
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/defryfazz/fazztalog/internal/device"
+)
+
+type DeviceRepository struct {
+	db *sql.DB
+}
+
+func NewDeviceRepository(db *sql.DB) *DeviceRepository {
+	return &DeviceRepository{
+		db: db,
+	}
+}
+
+func (r *DeviceRepository) ListDevices(ctx context.Context) ([]device.Device, error) {
+	query := `
+		SELECT phone, region, created_at, updated_at
+		FROM whatsapp_devices
+		ORDER BY phone
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []device.Device{}
+	for rows.Next() {
+		var d device.Device
+		if err := rows.Scan(&d.Phone, &d.Region, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range devices {
+		devices[i].MerchantIDs, err = r.getMerchantIDs(ctx, devices[i].Phone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return devices, nil
+}
+
+func (r *DeviceRepository) GetDeviceByPhone(ctx context.Context, phone string) (*device.Device, error) {
+	query := `
+		SELECT phone, region, created_at, updated_at
+		FROM whatsapp_devices
+		WHERE phone = ?
+	`
+	var res device.Device
+	err := r.db.QueryRowContext(ctx, query, phone).Scan(
+		&res.Phone,
+		&res.Region,
+		&res.CreatedAt,
+		&res.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	res.MerchantIDs, err = r.getMerchantIDs(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *DeviceRepository) getMerchantIDs(ctx context.Context, phone string) ([]string, error) {
+	query := `
+		SELECT merchant_id
+		FROM whatsapp_device_merchants
+		WHERE phone = ?
+		ORDER BY merchant_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, phone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	merchantIDs := []string{}
+	for rows.Next() {
+		var merchantID string
+		if err := rows.Scan(&merchantID); err != nil {
+			return nil, err
+		}
+		merchantIDs = append(merchantIDs, merchantID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return merchantIDs, nil
+}
+
+// UpsertDevice creates or updates the device and replaces its merchants in one
+// transaction.
+func (r *DeviceRepository) UpsertDevice(ctx context.Context, d device.Device) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO whatsapp_devices (phone, region, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (phone) DO UPDATE SET region = excluded.region, updated_at = excluded.updated_at
+	`, d.Phone, d.Region, d.CreatedAt, d.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM whatsapp_device_merchants WHERE phone = ?`, d.Phone); err != nil {
+		return err
+	}
+	for _, merchantID := range d.MerchantIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO whatsapp_device_merchants (phone, merchant_id)
+			VALUES (?, ?)
+		`, d.Phone, merchantID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *DeviceRepository) DeleteDevice(ctx context.Context, phone string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM whatsapp_device_merchants WHERE phone = ?`,
+		`DELETE FROM whatsapp_devices WHERE phone = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, phone); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,92 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/merchant"
+)
+
+type service struct {
+	repo            Repository
+	merchantService merchant.Service
+}
+
+func NewService(repo Repository, merchantService merchant.Service) Service {
+	return &service{
+		repo:            repo,
+		merchantService: merchantService,
+	}
+}
+
+func (s *service) ListDevices(ctx context.Context) ([]Device, error) {
+	return s.repo.ListDevices(ctx)
+}
+
+func (s *service) GetDevice(ctx context.Context, phone string) (*Device, error) {
+	device, err := s.repo.GetDeviceByPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+func (s *service) SetRouting(ctx context.Context, phone string, params RoutingParams) (*Device, error) {
+	for _, merchantID := range params.MerchantIDs {
+		if _, err := s.merchantService.GetMerchant(ctx, merchantID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	device, err := s.repo.GetDeviceByPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		device = &Device{Phone: phone, CreatedAt: now}
+	}
+	device.Region = params.Region
+	device.MerchantIDs = slices.Compact(slices.Sorted(slices.Values(params.MerchantIDs)))
+	device.UpdatedAt = now
+
+	if err := s.repo.UpsertDevice(ctx, *device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+func (s *service) DeleteDevice(ctx context.Context, phone string) error {
+	if _, err := s.GetDevice(ctx, phone); err != nil {
+		return err
+	}
+	return s.repo.DeleteDevice(ctx, phone)
+}
+
+func (s *service) Serves(ctx context.Context, phone string, merchantPhone string) (bool, error) {
+	device, err := s.repo.GetDeviceByPhone(ctx, phone)
+	if err != nil {
+		return false, err
+	}
+	if device == nil || (device.Region == "" && len(device.MerchantIDs) == 0) {
+		return true, nil
+	}
+
+	m, err := s.merchantService.GetMerchantByPhone(ctx, merchantPhone)
+	if err != nil {
+		if errors.Is(err, merchant.ErrMerchantNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if slices.Contains(device.MerchantIDs, m.ID) {
+		return true, nil
+	}
+	return device.Region != "" && device.Region == m.Region, nil
+}
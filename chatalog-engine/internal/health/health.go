@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Check reports whether a dependency is usable.
+type Check func(ctx context.Context) error
+
+// Checker runs the readiness checks of a process and serves the health,
+// readiness and metrics endpoints.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks map[string]Check
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  map[string]Check{},
+	}
+}
+
+// Add registers a readiness check under name.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Register adds /healthz, /readyz and /metrics to mux.
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", c.ServeHealthz)
+	mux.HandleFunc("GET /readyz", c.ServeReadyz)
+	mux.Handle("GET /metrics", promhttp.Handler())
+}
+
+// ServeHealthz reports that the process is alive. It does not check any
+// dependency, so a slow dependency never gets the process restarted.
+func (c *Checker) ServeHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ServeReadyz runs every check concurrently and reports 503 when one fails.
+func (c *Checker) ServeReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		res = readinessResponse{Status: "ok", Checks: map[string]string{}}
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := "ok"
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.Checks[name] = result
+			if result != "ok" {
+				res.Status = "unavailable"
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if res.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, res)
+}
+
+// Cached returns a check that reuses the last successful result of check for
+// ttl, so frequent probes do not hammer a remote API. Failures are never
+// cached.
+func Cached(ttl time.Duration, check Check) Check {
+	var (
+		mu     sync.Mutex
+		passed time.Time
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		fresh := time.Since(passed) < ttl
+		mu.Unlock()
+		if fresh {
+			return nil
+		}
+
+		if err := check(ctx); err != nil {
+			return err
+		}
+		mu.Lock()
+		passed = time.Now()
+		mu.Unlock()
+		return nil
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
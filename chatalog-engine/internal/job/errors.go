@@ -0,0 +1,5 @@
+package job
+
+import "errors"
+
+var ErrJobNotFound = errors.New("job not found")
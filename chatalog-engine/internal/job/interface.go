@@ -0,0 +1,25 @@
+package job
+
+import "context"
+
+type Service interface {
+	// CreateBrochureJob creates a brochure job for the given idempotency key. If a
+	// job already exists for the key, the existing job is returned instead and
+	// created is false.
+	CreateBrochureJob(ctx context.Context, params CreateBrochureJobParams) (job *Job, created bool, err error)
+	GetJob(ctx context.Context, jobID string) (*Job, error)
+	MarkGenerated(ctx context.Context, jobID string, filePath string) error
+	MarkSent(ctx context.Context, jobID string) error
+	MarkFailed(ctx context.Context, jobID string, cause error) error
+	// GetUnfinishedJobs returns jobs that were interrupted before their brochure
+	// was sent, e.g. by a shutdown, so they can be resumed.
+	GetUnfinishedJobs(ctx context.Context) ([]Job, error)
+}
+
+type Repository interface {
+	CreateJob(ctx context.Context, job Job) (bool, error)
+	GetJobByID(ctx context.Context, jobID string) (*Job, error)
+	GetJobByIdempotencyKey(ctx context.Context, key string) (*Job, error)
+	UpdateJobStatus(ctx context.Context, jobID string, status Status, filePath string, errMessage string) error
+	GetJobsByStatus(ctx context.Context, statuses ...Status) ([]Job, error)
+}
@@ -0,0 +1,54 @@
+package job
+
+import "time"
+
+type Status string
+
+const ChannelAPI = "api"
+
+const (
+	StatusPending   Status = "pending"
+	StatusGenerated Status = "generated"
+	StatusSent      Status = "sent"
+	StatusFailed    Status = "failed"
+)
+
+type Job struct {
+	ID             string
+	IdempotencyKey string
+	MerchantPhone  string
+	Channel        string
+	// AccountID is the channel account the brochure is sent from, e.g. the
+	// WhatsApp number that received the request.
+	AccountID    string
+	ChatID       string
+	ProductNames []string
+	Options      Options
+	Status       Status
+	FilePath     string
+	Error        string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type CreateBrochureJobParams struct {
+	IdempotencyKey string
+	MerchantPhone  string
+	Channel        string
+	AccountID      string
+	ChatID         string
+	ProductNames   []string
+	Options        Options
+}
+
+// Options holds the optional brochure settings of a job.
+type Options struct {
+	Items  []Item `json:"items,omitempty"`
+	Style  string `json:"style,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+type Item struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/job"
+)
+
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+func (r *JobRepository) CreateJob(ctx context.Context, j job.Job) (bool, error) {
+	productNames, err := json.Marshal(j.ProductNames)
+	if err != nil {
+		return false, err
+	}
+	options, err := json.Marshal(j.Options)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO brochure_jobs (id, idempotency_key, merchant_phone, channel, account_id, chat_id, product_names, options, status, file_path, error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query,
+		j.ID,
+		j.IdempotencyKey,
+		j.MerchantPhone,
+		j.Channel,
+		j.AccountID,
+		j.ChatID,
+		string(productNames),
+		string(options),
+		j.Status,
+		j.FilePath,
+		j.Error,
+		j.CreatedAt,
+		j.UpdatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *JobRepository) GetJobByID(ctx context.Context, jobID string) (*job.Job, error) {
+	query := `
+		SELECT id, idempotency_key, merchant_phone, channel, account_id, chat_id, product_names, options, status, file_path, error, created_at, updated_at
+		FROM brochure_jobs
+		WHERE id = ?
+	`
+	res, err := scanJob(r.db.QueryRowContext(ctx, query, jobID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (r *JobRepository) GetJobByIdempotencyKey(ctx context.Context, key string) (*job.Job, error) {
+	query := `
+		SELECT id, idempotency_key, merchant_phone, channel, account_id, chat_id, product_names, options, status, file_path, error, created_at, updated_at
+		FROM brochure_jobs
+		WHERE idempotency_key = ?
+	`
+	res, err := scanJob(r.db.QueryRowContext(ctx, query, key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (r *JobRepository) GetJobsByStatus(ctx context.Context, statuses ...job.Status) ([]job.Job, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", ")
+	query := `
+		SELECT id, idempotency_key, merchant_phone, channel, account_id, chat_id, product_names, options, status, file_path, error, created_at, updated_at
+		FROM brochure_jobs
+		WHERE status IN (` + placeholders + `)
+		ORDER BY created_at
+	`
+	args := make([]any, 0, len(statuses))
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []job.Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *JobRepository) UpdateJobStatus(ctx context.Context, jobID string, status job.Status, filePath string, errMessage string) error {
+	query := `
+		UPDATE brochure_jobs
+		SET status = ?,
+			file_path = COALESCE(NULLIF(?, ''), file_path),
+			error = ?,
+			updated_at = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, status, filePath, errMessage, time.Now(), jobID)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*job.Job, error) {
+	var (
+		res          job.Job
+		productNames string
+		options      sql.NullString
+	)
+	err := row.Scan(
+		&res.ID,
+		&res.IdempotencyKey,
+		&res.MerchantPhone,
+		&res.Channel,
+		&res.AccountID,
+		&res.ChatID,
+		&productNames,
+		&options,
+		&res.Status,
+		&res.FilePath,
+		&res.Error,
+		&res.CreatedAt,
+		&res.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(productNames), &res.ProductNames); err != nil {
+		return nil, err
+	}
+	if options.Valid && options.String != "" {
+		if err := json.Unmarshal([]byte(options.String), &res.Options); err != nil {
+			return nil, err
+		}
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,94 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/defryfazz/fazztalog/internal/metrics"
+	"github.com/google/uuid"
+)
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+func (s *service) CreateBrochureJob(ctx context.Context, params CreateBrochureJobParams) (*Job, bool, error) {
+	now := time.Now()
+	job := Job{
+		ID:             uuid.New().String(),
+		IdempotencyKey: params.IdempotencyKey,
+		MerchantPhone:  params.MerchantPhone,
+		Channel:        params.Channel,
+		AccountID:      params.AccountID,
+		ChatID:         params.ChatID,
+		ProductNames:   params.ProductNames,
+		Options:        params.Options,
+		Status:         StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	created, err := s.repo.CreateJob(ctx, job)
+	if err != nil {
+		return nil, false, err
+	}
+	if created {
+		return &job, true, nil
+	}
+
+	existing, err := s.repo.GetJobByIdempotencyKey(ctx, params.IdempotencyKey)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing == nil {
+		return nil, false, fmt.Errorf("job with idempotency key %s not found", params.IdempotencyKey)
+	}
+
+	return existing, false, nil
+}
+
+func (s *service) GetJob(ctx context.Context, jobID string) (*Job, error) {
+	job, err := s.repo.GetJobByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (s *service) MarkGenerated(ctx context.Context, jobID string, filePath string) error {
+	return s.updateStatus(ctx, jobID, StatusGenerated, filePath, "")
+}
+
+func (s *service) MarkSent(ctx context.Context, jobID string) error {
+	return s.updateStatus(ctx, jobID, StatusSent, "", "")
+}
+
+func (s *service) MarkFailed(ctx context.Context, jobID string, cause error) error {
+	errMessage := ""
+	if cause != nil {
+		errMessage = cause.Error()
+	}
+	return s.updateStatus(ctx, jobID, StatusFailed, "", errMessage)
+}
+
+func (s *service) updateStatus(ctx context.Context, jobID string, status Status, filePath string, errMessage string) error {
+	if err := s.repo.UpdateJobStatus(ctx, jobID, status, filePath, errMessage); err != nil {
+		return err
+	}
+	metrics.BrochureJobs.WithLabelValues(string(status)).Inc()
+	return nil
+}
+
+func (s *service) GetUnfinishedJobs(ctx context.Context) ([]Job, error) {
+	return s.repo.GetJobsByStatus(ctx, StatusPending, StatusGenerated)
+}
@@ -0,0 +1,113 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/defryfazz/fazztalog/internal/logger"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrQueueFull     = errors.New("job queue is full")
+	ErrWorkerStopped = errors.New("job worker is stopped")
+)
+
+// Worker runs queued jobs in the background with a fixed number of
+// goroutines. It is used for jobs that are not tied to a conversation, such
+// as brochures requested over HTTP.
+type Worker struct {
+	run         func(ctx context.Context, job Job)
+	queue       chan Job
+	concurrency int
+
+	mu      sync.Mutex
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func NewWorker(queueSize int, concurrency int, run func(ctx context.Context, job Job)) *Worker {
+	return &Worker{
+		run:         run,
+		queue:       make(chan Job, queueSize),
+		concurrency: concurrency,
+	}
+}
+
+// Start starts the worker goroutines. Jobs are run with ctx, so cancelling it
+// aborts the jobs in progress.
+func (w *Worker) Start(ctx context.Context) {
+	for range w.concurrency {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for job := range w.queue {
+				w.runJob(ctx, job)
+			}
+		}()
+	}
+}
+
+func (w *Worker) runJob(ctx context.Context, job Job) {
+	ctx = logger.WithCorrelationID(ctx, uuid.New().String())
+	ctx = zerolog.Ctx(ctx).With().Str("job_id", job.ID).Logger().WithContext(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			zerolog.Ctx(ctx).Error().Interface("panic", r).Msg("recovered from panic in job")
+		}
+	}()
+	w.run(ctx, job)
+}
+
+// Submit queues a job without blocking.
+func (w *Worker) Submit(job Job) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closing {
+		return ErrWorkerStopped
+	}
+
+	select {
+	case w.queue <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// QueueDepth returns the number of jobs waiting to be run.
+func (w *Worker) QueueDepth() int {
+	return len(w.queue)
+}
+
+// Shutdown stops accepting jobs and waits until the queued jobs are done or
+// ctx is done. Jobs left in the queue stay pending in the database and are
+// picked up again on the next start.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closing {
+		w.closing = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until all worker goroutines have returned.
+func (w *Worker) Wait() {
+	w.wg.Wait()
+}
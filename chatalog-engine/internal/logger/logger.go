@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+	stdlog "log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+type SetupParams struct {
+	// Level is a zerolog level name, e.g. "debug" or "info".
+	Level string
+	// Format is FormatJSON or FormatConsole.
+	Format string
+	// Secrets are redacted from every log line, e.g. API tokens.
+	Secrets []string
+}
+
+// Setup configures the global logger. Every line goes through the redactor,
+// including lines written with the standard library log package.
+func Setup(params SetupParams) {
+	level, err := zerolog.ParseLevel(strings.ToLower(params.Level))
+	if err != nil || params.Level == "" {
+		level = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(level)
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	zerolog.DurationFieldUnit = time.Millisecond
+	zerolog.DurationFieldInteger = true
+
+	redactor := NewRedactor(os.Stderr, params.Secrets...)
+	var logger zerolog.Logger
+	if params.Format == FormatConsole {
+		logger = zerolog.New(zerolog.ConsoleWriter{Out: redactor, TimeFormat: time.DateTime})
+	} else {
+		logger = zerolog.New(redactor)
+	}
+	logger = logger.With().Timestamp().Logger()
+
+	log.Logger = logger
+	zerolog.DefaultContextLogger = &log.Logger
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(logger)
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context whose logger tags every line with the
+// correlation ID, so all lines about one message or job can be found
+// together.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	ctx = context.WithValue(ctx, correlationIDKey{}, correlationID)
+	return zerolog.Ctx(ctx).With().Str("correlation_id", correlationID).Logger().WithContext(ctx)
+}
+
+// CorrelationID returns the correlation ID of ctx, or an empty string.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	tokenPatterns = []*regexp.Regexp{
+		// OpenAI API keys.
+		regexp.MustCompile(`sk-[A-Za-z0-9_\-]{16,}`),
+		// Telegram bot tokens, also inside API URLs.
+		regexp.MustCompile(`\d{5,12}:[A-Za-z0-9_\-]{30,}`),
+	}
+	bearerPattern     = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-~+/]+=*`)
+	queryParamPattern = regexp.MustCompile(`(?i)((?:access_token|token|secret|api_key|password)=)[^&\s"]+`)
+	// Phones are international (+62...), or Indonesian with the country code
+	// (62...) or the trunk prefix (08...). Other digit runs such as
+	// timestamps, counts and Telegram IDs are kept.
+	phonePattern = regexp.MustCompile(`\+\d{8,15}|(?:62|0)\d{6,13}`)
+)
+
+// Redactor is an io.Writer that removes secrets and phone numbers from each
+// write before passing it on. zerolog writes one line per call, so a secret
+// is never split across writes.
+type Redactor struct {
+	out io.Writer
+
+	mu      sync.RWMutex
+	secrets [][]byte
+}
+
+func NewRedactor(out io.Writer, secrets ...string) *Redactor {
+	r := &Redactor{out: out}
+	r.AddSecrets(secrets...)
+	return r
+}
+
+// AddSecrets registers values that are replaced verbatim. Values shorter than
+// six characters are ignored, they would redact unrelated text.
+func (r *Redactor) AddSecrets(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range secrets {
+		if len(secret) >= 6 {
+			r.secrets = append(r.secrets, []byte(secret))
+		}
+	}
+}
+
+func (r *Redactor) Write(p []byte) (int, error) {
+	if _, err := r.out.Write(r.Redact(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Redact returns p with secrets, tokens and phone numbers masked. Phone
+// numbers keep their last four digits so lines can still be told apart.
+func (r *Redactor) Redact(p []byte) []byte {
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		p = bytes.ReplaceAll(p, secret, []byte(redacted))
+	}
+	r.mu.RUnlock()
+
+	for _, pattern := range tokenPatterns {
+		p = pattern.ReplaceAll(p, []byte(redacted))
+	}
+	p = bearerPattern.ReplaceAll(p, []byte("${1}"+redacted))
+	p = queryParamPattern.ReplaceAll(p, []byte("${1}"+redacted))
+	return maskPhones(p)
+}
+
+// maskPhones masks phones of 8 to 15 digits that stand on their own, so
+// digits inside identifiers such as UUIDs or hex message IDs are kept.
+func maskPhones(p []byte) []byte {
+	matches := phonePattern.FindAllIndex(p, -1)
+	if matches == nil {
+		return p
+	}
+
+	var out bytes.Buffer
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if start > 0 && isIdentifierByte(p[start-1]) || end < len(p) && isIdentifierByte(p[end]) {
+			continue
+		}
+		out.Write(p[last:start])
+		digits := p[start:end]
+		keep := len(digits) - 4
+		for _, b := range digits[:keep] {
+			if b == '+' {
+				out.WriteByte(b)
+			} else {
+				out.WriteByte('*')
+			}
+		}
+		out.Write(digits[keep:])
+		last = end
+	}
+	out.Write(p[last:])
+	return out.Bytes()
+}
+
+func isIdentifierByte(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b == '-' || b == '.' || b == '_'
+}
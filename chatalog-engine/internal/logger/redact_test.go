@@ -0,0 +1,28 @@
+package logger
+
+import "testing"
+
+func TestRedactPhones(t *testing.T) {
+	r := NewRedactor(nil)
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`"phone":"628123456789"`, `"phone":"********6789"`},
+		{`"phone":"+628123456789"`, `"phone":"+********6789"`},
+		{`"phone":"08123456789"`, `"phone":"*******6789"`},
+		{`"chat_id":"628123456789@s.whatsapp.net"`, `"chat_id":"********6789@s.whatsapp.net"`},
+		{`"phone":"+14155550123"`, `"phone":"+*******0123"`},
+		// Digit runs that are not phones are kept.
+		{`"received_at":1700000000`, `"received_at":1700000000`},
+		{`"chat_id":"5551234567"`, `"chat_id":"5551234567"`},
+		{`"duration_ms":12345678`, `"duration_ms":12345678`},
+		{`"job_id":"3f6e0812345678ab"`, `"job_id":"3f6e0812345678ab"`},
+		{`"message_id":"wamid.628123456789"`, `"message_id":"wamid.628123456789"`},
+	}
+	for _, tt := range tests {
+		if got := string(r.Redact([]byte(tt.in))); got != tt.want {
+			t.Errorf("Redact(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package merchant
+
+import "errors"
+
+var (
+	ErrMerchantNotFound = errors.New("merchant not found")
+	ErrProductNotFound  = errors.New("product not found")
+	ErrProductExists    = errors.New("product id is already used by another product of the merchant")
+	ErrPhoneTaken       = errors.New("phone is already used by another merchant")
+	ErrAccountNotFound  = errors.New("account not found")
+	ErrAccountTaken     = errors.New("account is already linked to another merchant")
+	ErrImageNotFound    = errors.New("image not found")
+	ErrUnsupportedImage = errors.New("image must be a png, jpeg or webp")
+)
@@ -1,12 +1,81 @@
 package merchant
 
-import "context"
+import (
+	"context"
+	"io"
+
+	"github.com/defryfazz/fazztalog/internal/brochure"
+	"github.com/defryfazz/fazztalog/internal/storage"
+)
 
 type Service interface {
-	GenerateBrochure(ctx context.Context, merchantPhone string, productNames []string) (string, error)
+	// GenerateBrochure generates the brochure and records it in the merchant's
+	// brochure history.
+	GenerateBrochure(ctx context.Context, req BrochureRequest) (*brochure.Brochure, error)
+	// GetMerchantByAccount returns the merchant linked to a user of a channel
+	// that does not identify users by phone, e.g. a Telegram user ID.
+	GetMerchantByAccount(ctx context.Context, channel string, externalID string) (*Merchant, error)
+	GetMerchantByPhone(ctx context.Context, phone string) (*Merchant, error)
+
+	ListMerchants(ctx context.Context, page Page) ([]Merchant, int, error)
+	GetMerchant(ctx context.Context, id string) (*Merchant, error)
+	CreateMerchant(ctx context.Context, params MerchantParams) (*Merchant, error)
+	UpdateMerchant(ctx context.Context, id string, params MerchantParams) (*Merchant, error)
+	DeleteMerchant(ctx context.Context, id string) error
+	// SetMerchantLogo stores the logo, replacing the previous one.
+	SetMerchantLogo(ctx context.Context, id string, image Image) (*Merchant, error)
+	OpenMerchantLogo(ctx context.Context, id string) (io.ReadCloser, *storage.Object, error)
+
+	ListAccounts(ctx context.Context, merchantID string) ([]Account, error)
+	// LinkAccount links the channel user to the merchant. Linking an account
+	// that is already linked to the merchant does nothing.
+	LinkAccount(ctx context.Context, merchantID string, channel string, externalID string) (*Account, error)
+	UnlinkAccount(ctx context.Context, merchantID string, channel string, externalID string) error
+
+	ListProducts(ctx context.Context, merchantID string, page Page) ([]Product, int, error)
+	GetProduct(ctx context.Context, merchantID string, id string) (*Product, error)
+	CreateProduct(ctx context.Context, merchantID string, params ProductParams) (*Product, error)
+	UpdateProduct(ctx context.Context, merchantID string, id string, params ProductParams) (*Product, error)
+	DeleteProduct(ctx context.Context, merchantID string, id string) error
+	// ImportProducts creates or updates the given products in one transaction.
+	// Products with an ID that already exists for the merchant are updated.
+	ImportProducts(ctx context.Context, merchantID string, params []ProductParams) (*ImportResult, error)
+	// SyncProducts makes the merchant's synced products match the given
+	// products: new ones are added, changed ones updated and missing ones
+	// removed. Products created by hand are left alone, and so are synced
+	// products kept by params because their row could not be read.
+	SyncProducts(ctx context.Context, merchantID string, params SyncParams) (*SyncResult, error)
+	// SetProductPhoto stores the photo, replacing the previous one.
+	SetProductPhoto(ctx context.Context, merchantID string, id string, image Image) (*Product, error)
+	OpenProductPhoto(ctx context.Context, merchantID string, id string) (io.ReadCloser, *storage.Object, error)
 }
 
 type Repository interface {
 	GetMerchantByPhone(ctx context.Context, phone string) (*Merchant, error)
 	GetProductsByMerchantID(ctx context.Context, merchantID string) ([]Product, error)
+	GetMerchantByAccount(ctx context.Context, channel string, externalID string) (*Merchant, error)
+
+	ListMerchants(ctx context.Context, page Page) ([]Merchant, int, error)
+	GetMerchantByID(ctx context.Context, id string) (*Merchant, error)
+	CreateMerchant(ctx context.Context, m Merchant) error
+	UpdateMerchant(ctx context.Context, m Merchant) error
+	DeleteMerchant(ctx context.Context, id string) error
+	SetMerchantLogoKey(ctx context.Context, id string, key string) error
+
+	ListMerchantAccounts(ctx context.Context, merchantID string) ([]Account, error)
+	// CreateMerchantAccount returns false when the account is already linked.
+	CreateMerchantAccount(ctx context.Context, a Account) (bool, error)
+	// DeleteMerchantAccount returns false when the account was not linked to
+	// the merchant.
+	DeleteMerchantAccount(ctx context.Context, merchantID string, channel string, externalID string) (bool, error)
+
+	ListProducts(ctx context.Context, merchantID string, page Page) ([]Product, int, error)
+	GetProductByID(ctx context.Context, merchantID string, id string) (*Product, error)
+	CreateProduct(ctx context.Context, p Product) error
+	UpdateProduct(ctx context.Context, p Product) error
+	DeleteProduct(ctx context.Context, merchantID string, id string) error
+	UpsertProducts(ctx context.Context, merchantID string, products []Product) (*ImportResult, error)
+	// SyncProducts applies the changes of a sync in one transaction.
+	SyncProducts(ctx context.Context, merchantID string, changes SyncResult) error
+	SetProductPhotoKey(ctx context.Context, merchantID string, id string, key string) error
 }
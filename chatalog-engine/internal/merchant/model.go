@@ -1,9 +1,26 @@
 package merchant
 
+import "io"
+
 type Merchant struct {
 	ID    string
 	Name  string
 	Phone string
+	// Region groups merchants so a WhatsApp number can serve a whole region.
+	Region string
+	// LogoKey is the storage key of the logo, empty when there is none.
+	LogoKey string
+	// SheetURL is the CSV export URL of the merchant's own sheet. Merchants
+	// without one are synced from the shared sheet.
+	SheetURL string
+}
+
+// Account links a user of a channel that does not identify users by phone,
+// e.g. a Telegram user ID, to a merchant.
+type Account struct {
+	Channel    string
+	ExternalID string
+	MerchantID string
 }
 
 type Product struct {
@@ -11,4 +28,93 @@ type Product struct {
 	MerchantID string
 	Name       string
 	Price      float64
+	// PhotoKey is the storage key of the photo, empty when there is none.
+	PhotoKey string
+	// SourceID is the ID of the product in the sheet it was synced from, empty
+	// for products created by hand.
+	SourceID string
+}
+
+// BrochureRequest describes a brochure to generate for the merchant with the
+// given phone. When Items is set, the items are used as is instead of the
+// merchant's catalog.
+type BrochureRequest struct {
+	MerchantPhone string
+	// JobID is recorded with the brochure when it is generated for a job.
+	JobID        string
+	ProductNames []string
+	Items        []BrochureItem
+	Style        string
+	Format       string
+}
+
+type BrochureItem struct {
+	Name  string
+	Price float64
+}
+
+// Image is an uploaded logo or product photo.
+type Image struct {
+	Content io.Reader
+	// Size is -1 when unknown.
+	Size        int64
+	ContentType string
+}
+
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+type MerchantParams struct {
+	Name     string
+	Phone    string
+	Region   string
+	SheetURL string
+}
+
+type ProductParams struct {
+	ID    string
+	Name  string
+	Price float64
+}
+
+type ImportResult struct {
+	Created int
+	Updated int
+}
+
+// SourceProduct is a product of an external catalog, e.g. a row of the
+// merchant's sheet.
+type SourceProduct struct {
+	SourceID string
+	Name     string
+	Price    float64
+}
+
+// SyncParams are the products of a sync.
+type SyncParams struct {
+	Products []SourceProduct
+	// KeepSourceIDs are synced products missing from Products that must not
+	// be removed, e.g. rows of the sheet that could not be read.
+	KeepSourceIDs []string
+	// KeepAll keeps every synced product missing from Products, for when an
+	// unreadable row cannot be matched to a product.
+	KeepAll bool
+}
+
+// SyncResult lists the products changed by a sync.
+type SyncResult struct {
+	Added   []Product
+	Updated []ProductChange
+	Removed []Product
+	// Kept are the products missing from the sync that were kept.
+	Kept      []Product
+	Unchanged int
+}
+
+// ProductChange is a product updated by a sync.
+type ProductChange struct {
+	Before Product
+	After  Product
 }
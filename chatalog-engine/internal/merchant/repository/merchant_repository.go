@@ -19,7 +19,7 @@ func NewMerchantRepository(db *sql.DB) *MerchantRepository {
 
 func (r *MerchantRepository) GetMerchantByPhone(ctx context.Context, phone string) (*merchant.Merchant, error) {
 	query := `
-		SELECT id, name, phone
+		SELECT id, name, phone, region, logo_key, sheet_url
 		FROM merchants
 		WHERE phone = ?
 	`
@@ -28,6 +28,35 @@ func (r *MerchantRepository) GetMerchantByPhone(ctx context.Context, phone strin
 		&res.ID,
 		&res.Name,
 		&res.Phone,
+		&res.Region,
+		&res.LogoKey,
+		&res.SheetURL,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (r *MerchantRepository) GetMerchantByAccount(ctx context.Context, channel string, externalID string) (*merchant.Merchant, error) {
+	query := `
+		SELECT m.id, m.name, m.phone, m.region, m.logo_key, m.sheet_url
+		FROM merchants m
+		JOIN merchant_accounts a ON a.merchant_id = m.id
+		WHERE a.channel = ? AND a.external_id = ?
+	`
+	var res merchant.Merchant
+	err := r.db.QueryRowContext(ctx, query, channel, externalID).Scan(
+		&res.ID,
+		&res.Name,
+		&res.Phone,
+		&res.Region,
+		&res.LogoKey,
+		&res.SheetURL,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -41,7 +70,7 @@ func (r *MerchantRepository) GetMerchantByPhone(ctx context.Context, phone strin
 
 func (r *MerchantRepository) GetProductsByMerchantID(ctx context.Context, merchantID string) ([]merchant.Product, error) {
 	query := `
-		SELECT id, merchant_id, name, price
+		SELECT id, merchant_id, name, price, photo_key, source_id
 		FROM products
 		WHERE merchant_id = ?
 	`
@@ -54,7 +83,7 @@ func (r *MerchantRepository) GetProductsByMerchantID(ctx context.Context, mercha
 	var products []merchant.Product
 	for rows.Next() {
 		var p merchant.Product
-		err := rows.Scan(&p.ID, &p.MerchantID, &p.Name, &p.Price)
+		err := rows.Scan(&p.ID, &p.MerchantID, &p.Name, &p.Price, &p.PhotoKey, &p.SourceID)
 		if err != nil {
 			return nil, err
 		}
@@ -65,3 +94,364 @@ func (r *MerchantRepository) GetProductsByMerchantID(ctx context.Context, mercha
 	}
 	return products, nil
 }
+
+func (r *MerchantRepository) ListMerchants(ctx context.Context, page merchant.Page) ([]merchant.Merchant, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM merchants`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, name, phone, region, logo_key, sheet_url
+		FROM merchants
+		ORDER BY name, id
+		LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	merchants := []merchant.Merchant{}
+	for rows.Next() {
+		var m merchant.Merchant
+		if err := rows.Scan(&m.ID, &m.Name, &m.Phone, &m.Region, &m.LogoKey, &m.SheetURL); err != nil {
+			return nil, 0, err
+		}
+		merchants = append(merchants, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return merchants, total, nil
+}
+
+func (r *MerchantRepository) GetMerchantByID(ctx context.Context, id string) (*merchant.Merchant, error) {
+	query := `
+		SELECT id, name, phone, region, logo_key, sheet_url
+		FROM merchants
+		WHERE id = ?
+	`
+	var res merchant.Merchant
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&res.ID,
+		&res.Name,
+		&res.Phone,
+		&res.Region,
+		&res.LogoKey,
+		&res.SheetURL,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (r *MerchantRepository) CreateMerchant(ctx context.Context, m merchant.Merchant) error {
+	query := `
+		INSERT INTO merchants (id, name, phone, region, sheet_url)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := r.db.ExecContext(ctx, query, m.ID, m.Name, m.Phone, m.Region, m.SheetURL)
+	return err
+}
+
+func (r *MerchantRepository) UpdateMerchant(ctx context.Context, m merchant.Merchant) error {
+	query := `
+		UPDATE merchants
+		SET name = ?, phone = ?, region = ?, sheet_url = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, m.Name, m.Phone, m.Region, m.SheetURL, m.ID)
+	return err
+}
+
+func (r *MerchantRepository) SetMerchantLogoKey(ctx context.Context, id string, key string) error {
+	query := `
+		UPDATE merchants
+		SET logo_key = ?
+		WHERE id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, key, id)
+	return err
+}
+
+// DeleteMerchant deletes the merchant together with its products, linked
+// channel accounts, WhatsApp number routing and brochure history.
+func (r *MerchantRepository) DeleteMerchant(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM products WHERE merchant_id = ?`,
+		`DELETE FROM merchant_accounts WHERE merchant_id = ?`,
+		`DELETE FROM whatsapp_device_merchants WHERE merchant_id = ?`,
+		`DELETE FROM brochures WHERE merchant_id = ?`,
+		`DELETE FROM merchants WHERE id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *MerchantRepository) ListMerchantAccounts(ctx context.Context, merchantID string) ([]merchant.Account, error) {
+	query := `
+		SELECT channel, external_id, merchant_id
+		FROM merchant_accounts
+		WHERE merchant_id = ?
+		ORDER BY channel, external_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []merchant.Account{}
+	for rows.Next() {
+		var a merchant.Account
+		if err := rows.Scan(&a.Channel, &a.ExternalID, &a.MerchantID); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *MerchantRepository) CreateMerchantAccount(ctx context.Context, a merchant.Account) (bool, error) {
+	query := `
+		INSERT INTO merchant_accounts (channel, external_id, merchant_id)
+		VALUES (?, ?, ?)
+		ON CONFLICT (channel, external_id) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query, a.Channel, a.ExternalID, a.MerchantID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *MerchantRepository) DeleteMerchantAccount(ctx context.Context, merchantID string, channel string, externalID string) (bool, error) {
+	query := `
+		DELETE FROM merchant_accounts
+		WHERE merchant_id = ? AND channel = ? AND external_id = ?
+	`
+	res, err := r.db.ExecContext(ctx, query, merchantID, channel, externalID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *MerchantRepository) ListProducts(ctx context.Context, merchantID string, page merchant.Page) ([]merchant.Product, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM products WHERE merchant_id = ?`, merchantID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, merchant_id, name, price, photo_key, source_id
+		FROM products
+		WHERE merchant_id = ?
+		ORDER BY name, id
+		LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, merchantID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []merchant.Product{}
+	for rows.Next() {
+		var p merchant.Product
+		if err := rows.Scan(&p.ID, &p.MerchantID, &p.Name, &p.Price, &p.PhotoKey, &p.SourceID); err != nil {
+			return nil, 0, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+func (r *MerchantRepository) GetProductByID(ctx context.Context, merchantID string, id string) (*merchant.Product, error) {
+	query := `
+		SELECT id, merchant_id, name, price, photo_key, source_id
+		FROM products
+		WHERE merchant_id = ? AND id = ?
+	`
+	var res merchant.Product
+	err := r.db.QueryRowContext(ctx, query, merchantID, id).Scan(
+		&res.ID,
+		&res.MerchantID,
+		&res.Name,
+		&res.Price,
+		&res.PhotoKey,
+		&res.SourceID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// CreateProduct returns merchant.ErrProductExists when the merchant already
+// has a product with the ID.
+func (r *MerchantRepository) CreateProduct(ctx context.Context, p merchant.Product) error {
+	query := `
+		INSERT INTO products (id, merchant_id, name, price)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (merchant_id, id) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query, p.ID, p.MerchantID, p.Name, p.Price)
+	if err != nil {
+		return err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return merchant.ErrProductExists
+	}
+	return nil
+}
+
+func (r *MerchantRepository) UpdateProduct(ctx context.Context, p merchant.Product) error {
+	query := `
+		UPDATE products
+		SET name = ?, price = ?
+		WHERE merchant_id = ? AND id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, p.Name, p.Price, p.MerchantID, p.ID)
+	return err
+}
+
+func (r *MerchantRepository) DeleteProduct(ctx context.Context, merchantID string, id string) error {
+	query := `
+		DELETE FROM products
+		WHERE merchant_id = ? AND id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, merchantID, id)
+	return err
+}
+
+func (r *MerchantRepository) UpsertProducts(ctx context.Context, merchantID string, products []merchant.Product) (*merchant.ImportResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var res merchant.ImportResult
+	for _, p := range products {
+		updated, err := tx.ExecContext(ctx, `
+			UPDATE products
+			SET name = ?, price = ?
+			WHERE merchant_id = ? AND id = ?
+		`, p.Name, p.Price, merchantID, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := updated.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected > 0 {
+			res.Updated++
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO products (id, merchant_id, name, price)
+			VALUES (?, ?, ?, ?)
+		`, p.ID, merchantID, p.Name, p.Price)
+		if err != nil {
+			return nil, err
+		}
+		res.Created++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *MerchantRepository) SyncProducts(ctx context.Context, merchantID string, changes merchant.SyncResult) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, p := range changes.Added {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO products (id, merchant_id, name, price, source_id)
+			VALUES (?, ?, ?, ?, ?)
+		`, p.ID, merchantID, p.Name, p.Price, p.SourceID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, change := range changes.Updated {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE products
+			SET name = ?, price = ?
+			WHERE merchant_id = ? AND id = ?
+		`, change.After.Name, change.After.Price, merchantID, change.After.ID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range changes.Removed {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM products
+			WHERE merchant_id = ? AND id = ?
+		`, merchantID, p.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *MerchantRepository) SetProductPhotoKey(ctx context.Context, merchantID string, id string, key string) error {
+	query := `
+		UPDATE products
+		SET photo_key = ?
+		WHERE merchant_id = ? AND id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, key, merchantID, id)
+	return err
+}
@@ -2,31 +2,116 @@ package merchant
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
 
 	"github.com/defryfazz/fazztalog/internal/ai"
+	"github.com/defryfazz/fazztalog/internal/brochure"
+	"github.com/defryfazz/fazztalog/internal/storage"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 )
 
 type service struct {
-	repo     Repository
-	aiEngine ai.Engine
+	repo            Repository
+	aiEngine        ai.Engine
+	brochureService brochure.Service
+	storage         storage.Storage
 }
 
-func NewService(repo Repository, aiEngine ai.Engine) Service {
+type ServiceParams struct {
+	Repository      Repository
+	AIEngine        ai.Engine
+	BrochureService brochure.Service
+	// Storage keeps the brochures, logos and product photos.
+	Storage storage.Storage
+}
+
+func NewService(params ServiceParams) Service {
 	return &service{
-		repo:     repo,
-		aiEngine: aiEngine,
+		repo:            params.Repository,
+		aiEngine:        params.AIEngine,
+		brochureService: params.BrochureService,
+		storage:         params.Storage,
 	}
 }
 
-func (s *service) GenerateBrochure(ctx context.Context, merchantPhone string, productNames []string) (string, error) {
-	merchant, err := s.repo.GetMerchantByPhone(ctx, merchantPhone)
+func (s *service) GenerateBrochure(ctx context.Context, req BrochureRequest) (*brochure.Brochure, error) {
+	merchant, err := s.repo.GetMerchantByPhone(ctx, req.MerchantPhone)
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, ErrMerchantNotFound
+	}
+
+	aiProducts, err := s.brochureProducts(ctx, merchant.ID, req)
+	if err != nil {
+		return nil, err
+	}
+	zerolog.Ctx(ctx).Info().
+		Str("merchant_id", merchant.ID).
+		Int("products", len(aiProducts)).
+		Msg("generating brochure")
+
+	brochureDetails := ai.BrochureDetails{
+		MerchantName: merchant.Name,
+		Products:     aiProducts,
+		Style:        req.Style,
+		Format:       ai.BrochureFormat(req.Format),
+	}
+
+	generated, err := s.aiEngine.GenerateBrochure(ctx, brochureDetails)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	products, err := s.repo.GetProductsByMerchantID(ctx, merchant.ID)
+	// The engine writes the image to the temp folder, it is kept in storage.
+	key := storage.BrochureKey(merchant.ID, generated.FilePath)
+	err = storage.PutFile(ctx, s.storage, key, generated.FilePath)
+	os.Remove(generated.FilePath)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("error storing brochure: %w", err)
+	}
+
+	products := make([]brochure.Product, 0, len(aiProducts))
+	for _, p := range aiProducts {
+		products = append(products, brochure.Product{
+			Name:  p.Name,
+			Price: p.Price,
+		})
+	}
+	return s.brochureService.CreateBrochure(ctx, brochure.CreateBrochureParams{
+		MerchantID: merchant.ID,
+		JobID:      req.JobID,
+		Products:   products,
+		Style:      req.Style,
+		Format:     string(brochureDetails.Format),
+		Prompt:     generated.Prompt,
+		Engine:     generated.Engine,
+		FilePath:   key,
+	})
+}
+
+func (s *service) brochureProducts(ctx context.Context, merchantID string, req BrochureRequest) ([]ai.Product, error) {
+	if len(req.Items) > 0 {
+		aiProducts := make([]ai.Product, 0, len(req.Items))
+		for _, item := range req.Items {
+			aiProducts = append(aiProducts, ai.Product{
+				Name:  item.Name,
+				Price: item.Price,
+			})
+		}
+		return aiProducts, nil
+	}
+
+	products, err := s.repo.GetProductsByMerchantID(ctx, merchantID)
+	if err != nil {
+		return nil, err
 	}
 
 	aiProducts := make([]ai.Product, 0, len(products))
@@ -37,17 +122,420 @@ func (s *service) GenerateBrochure(ctx context.Context, merchantPhone string, pr
 		})
 	}
 
-	if len(productNames) > 0 {
-		aiProducts, err = s.aiEngine.MatchProducts(ctx, productNames, aiProducts)
+	if len(req.ProductNames) > 0 {
+		aiProducts, err = s.aiEngine.MatchProducts(ctx, req.ProductNames, aiProducts)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
-	brochureDetails := ai.BrochureDetails{
-		MerchantName: merchant.Name,
-		Products:     aiProducts,
+	return aiProducts, nil
+}
+
+func (s *service) GetMerchantByAccount(ctx context.Context, channel string, externalID string) (*Merchant, error) {
+	return s.repo.GetMerchantByAccount(ctx, channel, externalID)
+}
+
+func (s *service) GetMerchantByPhone(ctx context.Context, phone string) (*Merchant, error) {
+	merchant, err := s.repo.GetMerchantByPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, ErrMerchantNotFound
 	}
+	return merchant, nil
+}
 
-	return s.aiEngine.GenerateBrochure(ctx, brochureDetails)
+func (s *service) ListMerchants(ctx context.Context, page Page) ([]Merchant, int, error) {
+	return s.repo.ListMerchants(ctx, page)
+}
+
+func (s *service) GetMerchant(ctx context.Context, id string) (*Merchant, error) {
+	merchant, err := s.repo.GetMerchantByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, ErrMerchantNotFound
+	}
+	return merchant, nil
+}
+
+func (s *service) CreateMerchant(ctx context.Context, params MerchantParams) (*Merchant, error) {
+	if err := s.checkPhoneAvailable(ctx, "", params.Phone); err != nil {
+		return nil, err
+	}
+
+	merchant := Merchant{
+		ID:       uuid.New().String(),
+		Name:     params.Name,
+		Phone:    params.Phone,
+		Region:   params.Region,
+		SheetURL: params.SheetURL,
+	}
+	if err := s.repo.CreateMerchant(ctx, merchant); err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+func (s *service) UpdateMerchant(ctx context.Context, id string, params MerchantParams) (*Merchant, error) {
+	merchant, err := s.GetMerchant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPhoneAvailable(ctx, id, params.Phone); err != nil {
+		return nil, err
+	}
+
+	merchant.Name = params.Name
+	merchant.Phone = params.Phone
+	merchant.Region = params.Region
+	merchant.SheetURL = params.SheetURL
+	if err := s.repo.UpdateMerchant(ctx, *merchant); err != nil {
+		return nil, err
+	}
+	return merchant, nil
+}
+
+func (s *service) DeleteMerchant(ctx context.Context, id string) error {
+	merchant, err := s.GetMerchant(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteMerchant(ctx, id); err != nil {
+		return err
+	}
+
+	// The merchant is gone, leftover media is only logged.
+	if merchant.LogoKey != "" {
+		s.deleteMedia(ctx, merchant.LogoKey)
+	}
+	for _, prefix := range []string{
+		storage.PrefixProductPhotos + id + "/",
+		storage.PrefixBrochures + id + "/",
+	} {
+		objects, err := s.storage.List(ctx, prefix)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("prefix", prefix).Msg("error listing merchant media")
+			continue
+		}
+		for _, obj := range objects {
+			s.deleteMedia(ctx, obj.Key)
+		}
+	}
+	return nil
+}
+
+func (s *service) SetMerchantLogo(ctx context.Context, id string, image Image) (*Merchant, error) {
+	merchant, err := s.GetMerchant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ext, err := imageExtension(image.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	key := storage.LogoKey(id, ext)
+	if err := s.storage.Put(ctx, key, image.Content, image.Size, image.ContentType); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetMerchantLogoKey(ctx, id, key); err != nil {
+		return nil, err
+	}
+	if merchant.LogoKey != "" && merchant.LogoKey != key {
+		s.deleteMedia(ctx, merchant.LogoKey)
+	}
+	merchant.LogoKey = key
+	return merchant, nil
+}
+
+func (s *service) OpenMerchantLogo(ctx context.Context, id string) (io.ReadCloser, *storage.Object, error) {
+	merchant, err := s.GetMerchant(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.openImage(ctx, merchant.LogoKey)
+}
+
+func (s *service) ListAccounts(ctx context.Context, merchantID string) ([]Account, error) {
+	if _, err := s.GetMerchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMerchantAccounts(ctx, merchantID)
+}
+
+func (s *service) LinkAccount(ctx context.Context, merchantID string, channel string, externalID string) (*Account, error) {
+	if _, err := s.GetMerchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+
+	account := Account{
+		Channel:    channel,
+		ExternalID: externalID,
+		MerchantID: merchantID,
+	}
+	created, err := s.repo.CreateMerchantAccount(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return &account, nil
+	}
+
+	linked, err := s.repo.GetMerchantByAccount(ctx, channel, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if linked == nil || linked.ID != merchantID {
+		return nil, ErrAccountTaken
+	}
+	return &account, nil
+}
+
+func (s *service) UnlinkAccount(ctx context.Context, merchantID string, channel string, externalID string) error {
+	deleted, err := s.repo.DeleteMerchantAccount(ctx, merchantID, channel, externalID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+func (s *service) checkPhoneAvailable(ctx context.Context, merchantID string, phone string) error {
+	existing, err := s.repo.GetMerchantByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != merchantID {
+		return ErrPhoneTaken
+	}
+	return nil
+}
+
+func (s *service) ListProducts(ctx context.Context, merchantID string, page Page) ([]Product, int, error) {
+	if _, err := s.GetMerchant(ctx, merchantID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListProducts(ctx, merchantID, page)
+}
+
+func (s *service) GetProduct(ctx context.Context, merchantID string, id string) (*Product, error) {
+	product, err := s.repo.GetProductByID(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+	return product, nil
+}
+
+func (s *service) CreateProduct(ctx context.Context, merchantID string, params ProductParams) (*Product, error) {
+	if _, err := s.GetMerchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+
+	product := Product{
+		ID:         params.ID,
+		MerchantID: merchantID,
+		Name:       params.Name,
+		Price:      params.Price,
+	}
+	if product.ID == "" {
+		product.ID = uuid.New().String()
+	}
+	if err := s.repo.CreateProduct(ctx, product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (s *service) UpdateProduct(ctx context.Context, merchantID string, id string, params ProductParams) (*Product, error) {
+	product, err := s.GetProduct(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Name = params.Name
+	product.Price = params.Price
+	if err := s.repo.UpdateProduct(ctx, *product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *service) DeleteProduct(ctx context.Context, merchantID string, id string) error {
+	product, err := s.GetProduct(ctx, merchantID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteProduct(ctx, merchantID, id); err != nil {
+		return err
+	}
+	if product.PhotoKey != "" {
+		s.deleteMedia(ctx, product.PhotoKey)
+	}
+	return nil
+}
+
+func (s *service) ImportProducts(ctx context.Context, merchantID string, params []ProductParams) (*ImportResult, error) {
+	if _, err := s.GetMerchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+
+	products := make([]Product, 0, len(params))
+	for _, p := range params {
+		product := Product{
+			ID:         p.ID,
+			MerchantID: merchantID,
+			Name:       p.Name,
+			Price:      p.Price,
+		}
+		if product.ID == "" {
+			product.ID = uuid.New().String()
+		}
+		products = append(products, product)
+	}
+
+	return s.repo.UpsertProducts(ctx, merchantID, products)
+}
+
+func (s *service) SyncProducts(ctx context.Context, merchantID string, params SyncParams) (*SyncResult, error) {
+	if _, err := s.GetMerchant(ctx, merchantID); err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.GetProductsByMerchantID(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	synced := map[string]Product{}
+	for _, p := range existing {
+		if p.SourceID != "" {
+			synced[p.SourceID] = p
+		}
+	}
+
+	var res SyncResult
+	seen := map[string]bool{}
+	for _, p := range params.Products {
+		if p.SourceID == "" || seen[p.SourceID] {
+			continue
+		}
+		seen[p.SourceID] = true
+
+		current, ok := synced[p.SourceID]
+		switch {
+		case !ok:
+			res.Added = append(res.Added, Product{
+				ID:         uuid.New().String(),
+				MerchantID: merchantID,
+				Name:       p.Name,
+				Price:      p.Price,
+				SourceID:   p.SourceID,
+			})
+		case current.Name != p.Name || current.Price != p.Price:
+			updated := current
+			updated.Name = p.Name
+			updated.Price = p.Price
+			res.Updated = append(res.Updated, ProductChange{Before: current, After: updated})
+		default:
+			res.Unchanged++
+		}
+	}
+	keep := map[string]bool{}
+	for _, id := range params.KeepSourceIDs {
+		keep[id] = true
+	}
+	for _, p := range existing {
+		if p.SourceID == "" || seen[p.SourceID] {
+			continue
+		}
+		if params.KeepAll || keep[p.SourceID] {
+			res.Kept = append(res.Kept, p)
+			continue
+		}
+		res.Removed = append(res.Removed, p)
+	}
+
+	if err := s.repo.SyncProducts(ctx, merchantID, res); err != nil {
+		return nil, err
+	}
+	for _, p := range res.Removed {
+		if p.PhotoKey != "" {
+			s.deleteMedia(ctx, p.PhotoKey)
+		}
+	}
+	return &res, nil
+}
+
+func (s *service) SetProductPhoto(ctx context.Context, merchantID string, id string, image Image) (*Product, error) {
+	product, err := s.GetProduct(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	ext, err := imageExtension(image.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	key := storage.ProductPhotoKey(merchantID, id, ext)
+	if err := s.storage.Put(ctx, key, image.Content, image.Size, image.ContentType); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetProductPhotoKey(ctx, merchantID, id, key); err != nil {
+		return nil, err
+	}
+	if product.PhotoKey != "" && product.PhotoKey != key {
+		s.deleteMedia(ctx, product.PhotoKey)
+	}
+	product.PhotoKey = key
+	return product, nil
+}
+
+func (s *service) OpenProductPhoto(ctx context.Context, merchantID string, id string) (io.ReadCloser, *storage.Object, error) {
+	product, err := s.GetProduct(ctx, merchantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.openImage(ctx, product.PhotoKey)
+}
+
+func (s *service) openImage(ctx context.Context, key string) (io.ReadCloser, *storage.Object, error) {
+	if key == "" {
+		return nil, nil, ErrImageNotFound
+	}
+	r, obj, err := storage.Open(ctx, s.storage, key)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, nil, ErrImageNotFound
+	}
+	if obj != nil && obj.ContentType == "" {
+		obj.ContentType = mime.TypeByExtension(filepath.Ext(key))
+	}
+	return r, obj, err
+}
+
+func (s *service) deleteMedia(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("key", key).Msg("error deleting media")
+	}
+}
+
+func imageExtension(contentType string) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "image/png":
+		return ".png", nil
+	case "image/jpeg":
+		return ".jpg", nil
+	case "image/webp":
+		return ".webp", nil
+	default:
+		return "", ErrUnsupportedImage
+	}
 }
@@ -0,0 +1,16 @@
+package message
+
+import "context"
+
+type Service interface {
+	// MarkProcessed records the message as processed. It returns false when the
+	// message has already been processed before, e.g. when it is redelivered.
+	MarkProcessed(ctx context.Context, messageID, chatID, senderID string) (bool, error)
+	// IsProcessed reports whether the message has been marked as processed.
+	IsProcessed(ctx context.Context, messageID, chatID string) (bool, error)
+}
+
+type Repository interface {
+	CreateProcessedMessage(ctx context.Context, msg ProcessedMessage) (bool, error)
+	ProcessedMessageExists(ctx context.Context, messageID, chatID string) (bool, error)
+}
@@ -0,0 +1,10 @@
+package message
+
+import "time"
+
+type ProcessedMessage struct {
+	ID          string
+	ChatID      string
+	SenderID    string
+	ProcessedAt time.Time
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/defryfazz/fazztalog/internal/message"
+)
+
+type MessageRepository struct {
+	db *sql.DB
+}
+
+func NewMessageRepository(db *sql.DB) *MessageRepository {
+	return &MessageRepository{
+		db: db,
+	}
+}
+
+func (r *MessageRepository) CreateProcessedMessage(ctx context.Context, msg message.ProcessedMessage) (bool, error) {
+	query := `
+		INSERT INTO processed_messages (id, chat_id, sender_id, processed_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (id, chat_id) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query, msg.ID, msg.ChatID, msg.SenderID, msg.ProcessedAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *MessageRepository) ProcessedMessageExists(ctx context.Context, messageID, chatID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM processed_messages
+			WHERE id = ? AND chat_id = ?
+		)
+	`
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, messageID, chatID).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
@@ -0,0 +1,29 @@
+package message
+
+import (
+	"context"
+	"time"
+)
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+func (s *service) MarkProcessed(ctx context.Context, messageID, chatID, senderID string) (bool, error) {
+	return s.repo.CreateProcessedMessage(ctx, ProcessedMessage{
+		ID:          messageID,
+		ChatID:      chatID,
+		SenderID:    senderID,
+		ProcessedAt: time.Now(),
+	})
+}
+
+func (s *service) IsProcessed(ctx context.Context, messageID, chatID string) (bool, error) {
+	return s.repo.ProcessedMessageExists(ctx, messageID, chatID)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "chatalog"
+
+var (
+	MessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Inbound messages received, by channel.",
+	}, []string{"channel"})
+
+	IntentsDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "intents_detected_total",
+		Help:      "Intents detected from inbound messages, by intent.",
+	}, []string{"intent"})
+
+	BrochureJobs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "brochure_jobs_total",
+		Help:      "Brochure job transitions, by status (generated, sent, failed).",
+	}, []string{"status"})
+
+	OpenAIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "openai_request_duration_seconds",
+		Help:      "Latency of OpenAI API requests, by operation and result.",
+		// Image generation takes up to a minute, chat completions a few seconds.
+		Buckets: []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 60, 90, 120},
+	}, []string{"operation", "result"})
+
+	InFlightMessages = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "inflight_messages",
+		Help:      "Messages and resumed jobs currently being processed.",
+	})
+)
+
+// RegisterQueueDepth exposes the number of brochure jobs waiting in the
+// queue. depth is called on every scrape.
+func RegisterQueueDepth(depth func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "brochure_queue_depth",
+		Help:      "Brochure jobs waiting in the queue.",
+	}, func() float64 {
+		return float64(depth())
+	})
+}
+
+// Result returns the result label of an operation that returned err.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,8 @@
+package sheet
+
+import "errors"
+
+var (
+	ErrNotConfigured = errors.New("sheet sync is not configured")
+	ErrNoRows        = errors.New("sheet has no products for the merchant")
+)
@@ -0,0 +1,29 @@
+package sheet
+
+import (
+	"context"
+
+	"github.com/defryfazz/fazztalog/internal/merchant"
+)
+
+type Service interface {
+	// SyncMerchant replaces the merchant's synced products with the merchant's
+	// rows of its sheet. When the sheet has no valid rows for the merchant,
+	// nothing is changed and ErrNoRows is returned along with the result, which
+	// lists the skipped rows.
+	SyncMerchant(ctx context.Context, m merchant.Merchant) (*MerchantSync, error)
+	// SyncAll syncs every merchant linked to a sheet: merchants with their own
+	// sheet and merchants with rows in the shared sheet. A failing merchant
+	// does not stop the others, its error is in its MerchantSync.
+	SyncAll(ctx context.Context) (*SyncAllResult, error)
+}
+
+// Source reads sheets.
+type Source interface {
+	Fetch(ctx context.Context, sheetURL string) (*Sheet, error)
+}
+
+// Notifier tells a merchant what a scheduled sync changed.
+type Notifier interface {
+	NotifySheetSync(ctx context.Context, sync MerchantSync) error
+}
@@ -0,0 +1,273 @@
+package sheet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mcpPageSize is the number of rows asked per fetch_products call. The server
+// may cap it lower, pages are followed until the last one either way.
+const mcpPageSize = 200
+
+// MCPSource reads sheets through the mcp-sheet-go server, which it runs as a
+// child process speaking JSON-RPC over stdio.
+type MCPSource struct {
+	command []string
+	timeout time.Duration
+}
+
+type MCPSourceParams struct {
+	// Command is the server binary followed by its arguments.
+	Command []string
+	// Timeout bounds a whole fetch, including starting the server.
+	Timeout time.Duration
+}
+
+func NewMCPSource(params MCPSourceParams) *MCPSource {
+	return &MCPSource{
+		command: params.Command,
+		timeout: params.Timeout,
+	}
+}
+
+type mcpRequest struct {
+	Jsonrpc string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type mcpResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *mcpError       `json:"error"`
+}
+
+type mcpError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (e *mcpError) Error() string {
+	if len(e.Data) == 0 {
+		return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+	}
+	// The server sends the details as a plain string most of the time.
+	var detail string
+	if err := json.Unmarshal(e.Data, &detail); err != nil {
+		detail = string(e.Data)
+	}
+	return fmt.Sprintf("%s (%d): %s", e.Message, e.Code, detail)
+}
+
+type mcpToolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+type mcpProductPage struct {
+	Items []struct {
+		UserID   string  `json:"user_id"`
+		ID       string  `json:"id"`
+		Name     string  `json:"name"`
+		Price    float64 `json:"price"`
+		Currency string  `json:"currency"`
+		ImageURL *string `json:"image_url"`
+	} `json:"items"`
+	Total      int  `json:"total"`
+	NextOffset *int `json:"next_offset"`
+	Skipped    []struct {
+		Row    int    `json:"row"`
+		UserID string `json:"user_id"`
+		ID     string `json:"id"`
+		Reason string `json:"reason"`
+	} `json:"skipped"`
+}
+
+func (s *MCPSource) Fetch(ctx context.Context, sheetURL string) (*Sheet, error) {
+	if len(s.command) == 0 {
+		return nil, ErrNotConfigured
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting sheet server: %w", err)
+	}
+
+	conn := &mcpConn{w: stdin, r: bufio.NewReader(stdout)}
+	content, err := s.fetch(conn, sheetURL)
+	// Closing stdin ends the server's read loop.
+	stdin.Close()
+	waitErr := cmd.Wait()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("sheet server: %w", ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w (stderr: %s)", err, msg)
+		}
+		return nil, err
+	}
+	if waitErr != nil {
+		return nil, fmt.Errorf("sheet server exited: %w", waitErr)
+	}
+	return content, nil
+}
+
+func (s *MCPSource) fetch(conn *mcpConn, sheetURL string) (*Sheet, error) {
+	if _, err := conn.call("initialize", map[string]any{}); err != nil {
+		return nil, fmt.Errorf("error initializing sheet server: %w", err)
+	}
+
+	var content Sheet
+	offset := 0
+	for {
+		result, err := conn.call("tools/call", map[string]any{
+			"name": "fetch_products",
+			"arguments": map[string]any{
+				"sheet_url": sheetURL,
+				"limit":     mcpPageSize,
+				"offset":    offset,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching products: %w", err)
+		}
+
+		var toolResult mcpToolResult
+		if err := json.Unmarshal(result, &toolResult); err != nil {
+			return nil, fmt.Errorf("invalid fetch_products result: %w", err)
+		}
+		if len(toolResult.Content) == 0 {
+			return nil, errors.New("fetch_products returned no content")
+		}
+		var page mcpProductPage
+		if err := json.Unmarshal([]byte(toolResult.Content[0].Text), &page); err != nil {
+			return nil, fmt.Errorf("invalid fetch_products page: %w", err)
+		}
+
+		for _, item := range page.Items {
+			row := Row{
+				UserID:   item.UserID,
+				ID:       item.ID,
+				Name:     item.Name,
+				Price:    item.Price,
+				Currency: item.Currency,
+			}
+			if item.ImageURL != nil {
+				row.ImageURL = *item.ImageURL
+			}
+			content.Rows = append(content.Rows, row)
+		}
+		for _, skipped := range page.Skipped {
+			content.Skipped = append(content.Skipped, SkippedRow{
+				Row:    skipped.Row,
+				UserID: skipped.UserID,
+				ID:     skipped.ID,
+				Reason: skipped.Reason,
+			})
+		}
+		if page.NextOffset == nil || *page.NextOffset <= offset {
+			return &content, nil
+		}
+		offset = *page.NextOffset
+	}
+}
+
+// mcpConn exchanges Content-Length framed JSON-RPC messages with the server,
+// one request at a time.
+type mcpConn struct {
+	w      io.Writer
+	r      *bufio.Reader
+	nextID int
+}
+
+func (c *mcpConn) call(method string, params any) (json.RawMessage, error) {
+	c.nextID++
+	body, err := json.Marshal(mcpRequest{
+		Jsonrpc: "2.0",
+		ID:      c.nextID,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/json\r\n\r\n", len(body))
+	if _, err := io.WriteString(c.w, header); err != nil {
+		return nil, err
+	}
+	if _, err := c.w.Write(body); err != nil {
+		return nil, err
+	}
+
+	frame, err := c.readFrame()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	var resp mcpResponse
+	if err := json.Unmarshal(frame, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	if resp.ID != c.nextID {
+		return nil, fmt.Errorf("response id %d does not match request id %d", resp.ID, c.nextID)
+	}
+	return resp.Result, nil
+}
+
+func (c *mcpConn) readFrame() ([]byte, error) {
+	contentLength := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "content-length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+			contentLength = n
+		}
+	}
+	if contentLength < 0 {
+		return nil, errors.New("missing Content-Length")
+	}
+	buf := make([]byte, contentLength)
+	if _, err := io.ReadFull(c.r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,50 @@
+package sheet
+
+import "github.com/defryfazz/fazztalog/internal/merchant"
+
+// Sheet is the content of a sheet: the rows that were parsed and the rows
+// that were not.
+type Sheet struct {
+	Rows    []Row
+	Skipped []SkippedRow
+}
+
+// Row is a product row of the sheet.
+type Row struct {
+	// UserID is the phone of the merchant the row belongs to.
+	UserID   string
+	ID       string
+	Name     string
+	Price    float64
+	Currency string
+	ImageURL string
+}
+
+// SkippedRow is a row that could not be parsed, e.g. because of an invalid
+// price.
+type SkippedRow struct {
+	// Row is the row number in the sheet, the header is row 1.
+	Row    int
+	UserID string
+	ID     string
+	Reason string
+}
+
+// MerchantSync is the outcome of syncing one merchant.
+type MerchantSync struct {
+	Merchant merchant.Merchant
+	// Result is nil when the catalog was not changed because of Err.
+	Result  *merchant.SyncResult
+	Skipped []SkippedRow
+	Err     error
+}
+
+// SyncAllResult is the outcome of syncing every linked merchant.
+type SyncAllResult struct {
+	Merchants []MerchantSync
+	// UnknownUsers are user IDs of the shared sheet that match no merchant.
+	UnknownUsers []string
+	// SharedErr is the error fetching the shared sheet, its merchants were not
+	// synced.
+	SharedErr error
+}
@@ -0,0 +1,106 @@
+package sheet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Scheduler periodically syncs every linked sheet and tells merchants what
+// changed.
+type Scheduler struct {
+	service  Service
+	notifier Notifier
+	interval time.Duration
+
+	// skipped holds the skipped rows last reported to each merchant, so rows
+	// that stay broken are not reported on every sync.
+	skipped map[string]string
+}
+
+type SchedulerParams struct {
+	Service  Service
+	Notifier Notifier
+	// Interval is the time between syncs. The scheduler does not run when it
+	// is zero.
+	Interval time.Duration
+}
+
+func NewScheduler(params SchedulerParams) *Scheduler {
+	return &Scheduler{
+		service:  params.Service,
+		notifier: params.Notifier,
+		interval: params.Interval,
+		skipped:  map[string]string{},
+	}
+}
+
+// Run syncs right away and then every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		log.Info().Msg("scheduled sheet sync is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.Sync(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync syncs every linked sheet once and notifies the merchants whose catalog
+// changed or whose sheet has new rows that could not be parsed.
+func (s *Scheduler) Sync(ctx context.Context) {
+	res, err := s.service.SyncAll(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error syncing sheets")
+		return
+	}
+	if res.SharedErr != nil {
+		log.Error().Err(res.SharedErr).Msg("error fetching shared sheet")
+	}
+
+	for _, sync := range res.Merchants {
+		logger := log.With().Str("merchant_id", sync.Merchant.ID).Logger()
+		if sync.Err != nil && !errors.Is(sync.Err, ErrNoRows) {
+			logger.Error().Err(sync.Err).Msg("error syncing merchant sheet")
+			continue
+		}
+
+		changed := sync.Result != nil &&
+			len(sync.Result.Added)+len(sync.Result.Updated)+len(sync.Result.Removed) > 0
+		skipped := skippedKey(sync.Skipped)
+		newSkipped := skipped != "" && skipped != s.skipped[sync.Merchant.ID]
+		s.skipped[sync.Merchant.ID] = skipped
+		if !changed && !newSkipped {
+			continue
+		}
+
+		if err := s.notifier.NotifySheetSync(ctx, sync); err != nil {
+			logger.Error().Err(err).Msg("error notifying merchant of sheet sync")
+			continue
+		}
+		logger.Info().Bool("changed", changed).Int("skipped", len(sync.Skipped)).Msg("merchant notified of sheet sync")
+	}
+	if len(res.UnknownUsers) > 0 {
+		log.Warn().Strs("user_ids", res.UnknownUsers).Msg("shared sheet has rows of unknown merchants")
+	}
+}
+
+func skippedKey(rows []SkippedRow) string {
+	var b strings.Builder
+	for _, row := range rows {
+		fmt.Fprintf(&b, "%d:%s:%s\n", row.Row, row.ID, row.Reason)
+	}
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package sheet
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/defryfazz/fazztalog/internal/merchant"
+	"github.com/rs/zerolog"
+)
+
+// merchantPageSize is the page size used to load every merchant for SyncAll.
+const merchantPageSize = 100
+
+type service struct {
+	source          Source
+	sharedURL       string
+	merchantService merchant.Service
+}
+
+type ServiceParams struct {
+	// Source is nil when sheet sync is not configured.
+	Source Source
+	// SharedURL is the sheet of the merchants without their own sheet. Rows
+	// belong to the merchant whose phone is in the user_id column.
+	SharedURL       string
+	MerchantService merchant.Service
+}
+
+func NewService(params ServiceParams) Service {
+	return &service{
+		source:          params.Source,
+		sharedURL:       params.SharedURL,
+		merchantService: params.MerchantService,
+	}
+}
+
+func (s *service) SyncMerchant(ctx context.Context, m merchant.Merchant) (*MerchantSync, error) {
+	sheetURL := m.SheetURL
+	if sheetURL == "" {
+		sheetURL = s.sharedURL
+	}
+	if s.source == nil || sheetURL == "" {
+		return nil, ErrNotConfigured
+	}
+	content, err := s.source.Fetch(ctx, sheetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	sync := s.syncMerchant(ctx, m, content)
+	return &sync, sync.Err
+}
+
+func (s *service) SyncAll(ctx context.Context) (*SyncAllResult, error) {
+	if s.source == nil {
+		return nil, ErrNotConfigured
+	}
+	merchants, err := s.allMerchants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		res  SyncAllResult
+		own  = map[string][]merchant.Merchant{}
+		urls []string
+	)
+	for _, m := range merchants {
+		if m.SheetURL == "" {
+			continue
+		}
+		if _, ok := own[m.SheetURL]; !ok {
+			urls = append(urls, m.SheetURL)
+		}
+		own[m.SheetURL] = append(own[m.SheetURL], m)
+	}
+
+	// Each sheet is fetched once, however many merchants it holds.
+	for _, sheetURL := range urls {
+		content, err := s.source.Fetch(ctx, sheetURL)
+		for _, m := range own[sheetURL] {
+			if err != nil {
+				res.Merchants = append(res.Merchants, MerchantSync{Merchant: m, Err: err})
+				continue
+			}
+			res.Merchants = append(res.Merchants, s.syncMerchant(ctx, m, content))
+		}
+	}
+
+	if s.sharedURL != "" {
+		content, err := s.source.Fetch(ctx, s.sharedURL)
+		if err != nil {
+			res.SharedErr = err
+			return &res, nil
+		}
+
+		users := map[string]string{}
+		for _, row := range content.Rows {
+			users[NormalizePhone(row.UserID)] = row.UserID
+		}
+		for _, row := range content.Skipped {
+			users[NormalizePhone(row.UserID)] = row.UserID
+		}
+		delete(users, "")
+		for _, m := range merchants {
+			phone := NormalizePhone(m.Phone)
+			if _, ok := users[phone]; !ok {
+				continue
+			}
+			delete(users, phone)
+			if m.SheetURL == "" {
+				res.Merchants = append(res.Merchants, s.syncMerchant(ctx, m, content))
+			}
+		}
+		for _, userID := range users {
+			res.UnknownUsers = append(res.UnknownUsers, userID)
+		}
+		sort.Strings(res.UnknownUsers)
+	}
+	return &res, nil
+}
+
+// syncMerchant applies the merchant's rows of the sheet. On the shared sheet
+// only rows with the merchant's phone belong to the merchant, on its own sheet
+// rows without user_id do too.
+func (s *service) syncMerchant(ctx context.Context, m merchant.Merchant, content *Sheet) MerchantSync {
+	phone := NormalizePhone(m.Phone)
+	belongs := func(userID string) bool {
+		if userID == "" {
+			return m.SheetURL != ""
+		}
+		return NormalizePhone(userID) == phone
+	}
+
+	sync := MerchantSync{Merchant: m}
+	var products []merchant.SourceProduct
+	for _, row := range content.Rows {
+		if belongs(row.UserID) {
+			products = append(products, merchant.SourceProduct{
+				SourceID: row.ID,
+				Name:     row.Name,
+				Price:    row.Price,
+			})
+		}
+	}
+	// The products of unreadable rows are kept until the rows are fixed. A row
+	// without an id cannot be matched to its product, so nothing is removed.
+	params := merchant.SyncParams{Products: products}
+	for _, row := range content.Skipped {
+		if belongs(row.UserID) {
+			sync.Skipped = append(sync.Skipped, row)
+			if row.ID == "" {
+				params.KeepAll = true
+			} else {
+				params.KeepSourceIDs = append(params.KeepSourceIDs, row.ID)
+			}
+		}
+	}
+
+	// A sheet without the merchant's rows is more likely a wrong phone or a
+	// broken sheet than an emptied catalog, so nothing is removed.
+	if len(products) == 0 {
+		sync.Err = ErrNoRows
+		return sync
+	}
+	sync.Result, sync.Err = s.merchantService.SyncProducts(ctx, m.ID, params)
+	if sync.Err != nil {
+		zerolog.Ctx(ctx).Error().Err(sync.Err).Str("merchant_id", m.ID).Msg("error syncing merchant sheet")
+	}
+	return sync
+}
+
+func (s *service) allMerchants(ctx context.Context) ([]merchant.Merchant, error) {
+	var merchants []merchant.Merchant
+	for offset := 0; ; offset += merchantPageSize {
+		page, total, err := s.merchantService.ListMerchants(ctx, merchant.Page{
+			Limit:  merchantPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		merchants = append(merchants, page...)
+		if len(page) == 0 || offset+len(page) >= total {
+			return merchants, nil
+		}
+	}
+}
+
+// NormalizePhone reduces a phone to its digits so the user_id of the sheet
+// matches the merchant phone however either is written. A leading 0 is the
+// Indonesian trunk prefix and is replaced by the 62 country code.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if strings.HasPrefix(digits, "0") {
+		digits = "62" + digits[1:]
+	}
+	return digits
+}
@@ -0,0 +1,8 @@
+package storage
+
+import "errors"
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrInvalidKey     = errors.New("invalid object key")
+)
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// localPather is implemented by storages that keep objects as local files.
+type localPather interface {
+	Path(key string) (string, error)
+}
+
+// LocalFile returns a local file with the content of the object, for channels
+// that send media from a file. Objects of a local storage are used in place,
+// other objects are downloaded into tempDir. The returned cleanup function must
+// be called once the file is no longer needed.
+//
+// Absolute paths are files written before media was kept in storage and are
+// returned as is.
+func LocalFile(ctx context.Context, s Storage, key string, tempDir string) (string, func(), error) {
+	noop := func() {}
+	if filepath.IsAbs(key) {
+		if _, err := os.Stat(key); err != nil {
+			return "", noop, ErrObjectNotFound
+		}
+		return key, noop, nil
+	}
+	if lp, ok := s.(localPather); ok {
+		p, err := lp.Path(key)
+		if err != nil {
+			return "", noop, err
+		}
+		if _, err := os.Stat(p); errors.Is(err, fs.ErrNotExist) {
+			return "", noop, ErrObjectNotFound
+		}
+		return p, noop, nil
+	}
+
+	src, err := s.Open(ctx, key)
+	if err != nil {
+		return "", noop, err
+	}
+	defer src.Close()
+
+	dir := filepath.Join(tempDir, "downloads")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", noop, err
+	}
+	f, err := os.CreateTemp(dir, "*"+path.Ext(key))
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		cleanup()
+		return "", noop, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return f.Name(), cleanup, nil
+}
+
+// Open returns the content of the object and its details. Like LocalFile, it
+// accepts the absolute paths of files written before media was kept in
+// storage.
+func Open(ctx context.Context, s Storage, key string) (io.ReadCloser, *Object, error) {
+	if filepath.IsAbs(key) {
+		f, err := os.Open(key)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrObjectNotFound
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return f, &Object{
+			Key:         key,
+			Size:        info.Size(),
+			ContentType: mime.TypeByExtension(filepath.Ext(key)),
+			ModTime:     info.ModTime(),
+		}, nil
+	}
+
+	obj, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, obj, nil
+}
+
+// Exists reports whether the object exists. Like LocalFile, it accepts the
+// absolute paths of files written before media was kept in storage.
+func Exists(ctx context.Context, s Storage, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	if filepath.IsAbs(key) {
+		_, err := os.Stat(key)
+		return err == nil, nil
+	}
+	_, err := s.Stat(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// PutFile stores the file at filePath under key.
+func PutFile(ctx context.Context, s Storage, key string, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, key, f, info.Size(), mime.TypeByExtension(filepath.Ext(filePath)))
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Storage keeps media files such as brochures, product photos and logos by
+// key. Keys are slash separated relative paths, e.g. "brochures/<id>.png".
+type Storage interface {
+	// Put stores the content of r under key, replacing any existing object.
+	// size is -1 when unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the content of the object, or ErrObjectNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat returns the object without its content, or ErrObjectNotFound.
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Pinger is implemented by remote storages that can become unreachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Janitor periodically removes stale temp files and expired brochures.
+type Janitor struct {
+	storage           Storage
+	tempDirs          []string
+	exclude           []string
+	tempRetention     time.Duration
+	brochureRetention time.Duration
+	interval          time.Duration
+}
+
+type JanitorParams struct {
+	Storage       Storage
+	TempDirectory string
+	// TempSubdirectories are the directories inside the temp directory that
+	// hold the app's temp files. Only these are cleaned, anything else in the
+	// temp directory is left alone.
+	TempSubdirectories []string
+	// Exclude lists files and directories that are never cleaned, e.g. the
+	// root of a local storage or a database. The journal files next to an
+	// excluded database are kept too.
+	Exclude []string
+	// TempRetention is how long files in the temp directory are kept.
+	TempRetention time.Duration
+	// BrochureRetention is how long brochures are kept in storage. Brochures
+	// are kept forever when it is zero.
+	BrochureRetention time.Duration
+	Interval          time.Duration
+}
+
+func NewJanitor(params JanitorParams) *Janitor {
+	exclude := make([]string, 0, len(params.Exclude))
+	for _, p := range params.Exclude {
+		if abs, err := filepath.Abs(p); err == nil {
+			exclude = append(exclude, abs)
+		}
+	}
+	tempDirs := make([]string, 0, len(params.TempSubdirectories))
+	for _, dir := range params.TempSubdirectories {
+		tempDirs = append(tempDirs, filepath.Join(params.TempDirectory, dir))
+	}
+	return &Janitor{
+		storage:           params.Storage,
+		tempDirs:          tempDirs,
+		exclude:           exclude,
+		tempRetention:     params.TempRetention,
+		brochureRetention: params.BrochureRetention,
+		interval:          params.Interval,
+	}
+}
+
+// Run cleans up right away and then every interval until ctx is done.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		j.Clean(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Clean removes the temp files and brochures that are older than their
+// retention.
+func (j *Janitor) Clean(ctx context.Context) {
+	now := time.Now()
+	for _, dir := range j.tempDirs {
+		if removed, err := j.cleanTempDir(dir, now.Add(-j.tempRetention)); err != nil {
+			log.Error().Err(err).Str("dir", dir).Msg("error cleaning temp directory")
+		} else if removed > 0 {
+			log.Info().Int("removed", removed).Str("dir", dir).Msg("removed stale temp files")
+		}
+	}
+
+	if j.brochureRetention <= 0 {
+		return
+	}
+	if removed, err := j.cleanPrefix(ctx, PrefixBrochures, now.Add(-j.brochureRetention)); err != nil {
+		log.Error().Err(err).Msg("error cleaning expired brochures")
+	} else if removed > 0 {
+		log.Info().Int("removed", removed).Msg("removed expired brochures")
+	}
+}
+
+func (j *Janitor) cleanTempDir(dir string, before time.Time) (int, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if slices.Contains(j.exclude, p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if j.excluded(p) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().Before(before) {
+			if err := os.Remove(p); err == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// excluded reports whether the file is excluded, or is the journal of an
+// excluded sqlite database.
+func (j *Janitor) excluded(p string) bool {
+	for _, ex := range j.exclude {
+		if p == ex || p == ex+"-wal" || p == ex+"-shm" || p == ex+"-journal" {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Janitor) cleanPrefix(ctx context.Context, prefix string, before time.Time) (int, error) {
+	objects, err := j.storage.List(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, obj := range objects {
+		if !obj.ModTime.Before(before) {
+			continue
+		}
+		if err := j.storage.Delete(ctx, obj.Key); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
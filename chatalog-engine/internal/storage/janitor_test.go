@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJanitorCleansOnlyTempSubdirectories(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	write := func(name string, modTime time.Time) string {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	stale := []string{
+		write("openai/brochure.png", old),
+		write("transcriptions/voice.ogg", old),
+		write("downloads/photo.jpg", old),
+	}
+	kept := []string{
+		write("openai/fresh.png", time.Now()),
+		write("notes.txt", old),
+		write("chatalog.db", old),
+		write("backups/chatalog.db", old),
+		write("media/brochures/old.png", old),
+		write("downloads/whatsmeow.db", old),
+		write("downloads/whatsmeow.db-wal", old),
+	}
+
+	janitor := NewJanitor(JanitorParams{
+		TempDirectory:      root,
+		TempSubdirectories: []string{"openai", "transcriptions", "downloads", "missing"},
+		Exclude:            []string{filepath.Join(root, "chatalog.db"), filepath.Join(root, "downloads/whatsmeow.db")},
+		TempRetention:      time.Hour,
+		Interval:           time.Hour,
+	})
+	janitor.Clean(context.Background())
+
+	for _, p := range stale {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", p)
+		}
+	}
+	for _, p := range kept {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s was removed: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"path"
+	"time"
+)
+
+// Key prefixes of the media kinds kept in storage. Only brochures expire,
+// product photos and logos are kept until they are replaced.
+const (
+	PrefixBrochures     = "brochures/"
+	PrefixProductPhotos = "products/"
+	PrefixLogos         = "logos/"
+)
+
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// BrochureKey returns the key of a brochure image of the merchant. fileName is
+// the base name of the generated file, e.g. "<uuid>.png".
+func BrochureKey(merchantID string, fileName string) string {
+	return PrefixBrochures + merchantID + "/" + path.Base(fileName)
+}
+
+// ProductPhotoKey returns the key of a product photo. ext includes the dot.
+func ProductPhotoKey(merchantID string, productID string, ext string) string {
+	return PrefixProductPhotos + merchantID + "/" + productID + ext
+}
+
+// LogoKey returns the key of a merchant logo. ext includes the dot.
+func LogoKey(merchantID string, ext string) string {
+	return PrefixLogos + merchantID + ext
+}
@@ -3,6 +3,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -56,7 +57,12 @@ func main() {
 	userID := flag.String("user", "", "filter user_id (nomor HP), opsional")
 	limit := flag.Int("limit", 5, "limit item")
 	offset := flag.Int("offset", 0, "offset item")
+	framing := flag.String("framing", "ndjson", "framing stdio: ndjson atau content-length (server lama)")
 	flag.Parse()
+	if *framing != "ndjson" && *framing != "content-length" {
+		fmt.Fprintf(os.Stderr, "framing tidak dikenal %q (ndjson atau content-length)\n", *framing)
+		os.Exit(2)
+	}
 
 	// Spawn server MCP
 	cmd := exec.Command(*serverPath)
@@ -77,10 +83,13 @@ func main() {
 	r := bufio.NewReader(stdout)
 
 	// 1) initialize
-	sendJSONRPC(w, map[string]any{
+	sendJSONRPC(w, *framing, map[string]any{
 		"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{},
 	})
-	_, _ = readFrame(r) // boleh diabaikan
+	_, _ = readMessage(r, *framing) // boleh diabaikan
+	sendJSONRPC(w, *framing, map[string]any{
+		"jsonrpc": "2.0", "method": "notifications/initialized",
+	})
 
 	// 2) tools/call fetch_products
 	args := map[string]any{"limit": *limit, "offset": *offset}
@@ -90,7 +99,7 @@ func main() {
 	// Jika tidak set env SHEET_URL di atas, bisa kirim sheet_url di args:
 	// if *sheetURL != "" { args["sheet_url"] = *sheetURL }
 
-	sendJSONRPC(w, map[string]any{
+	sendJSONRPC(w, *framing, map[string]any{
 		"jsonrpc": "2.0",
 		"id":      2,
 		"method":  "tools/call",
@@ -100,7 +109,7 @@ func main() {
 		},
 	})
 
-	respBody, err := readFrame(r)
+	respBody, err := readMessage(r, *framing)
 	must(err)
 
 	var rpc rpcResp
@@ -140,15 +149,42 @@ func main() {
 	_ = cmd.Process.Kill()
 }
 
-// ---------- framing: write/read LSP-style frames ----------
-func sendJSONRPC(w *bufio.Writer, payload any) {
+// ---------- framing: NDJSON (spec MCP) atau LSP-style frames ----------
+func sendJSONRPC(w *bufio.Writer, framing string, payload any) {
 	body, _ := json.Marshal(payload)
+	if framing == "ndjson" {
+		w.Write(body)
+		w.WriteByte('\n')
+		w.Flush()
+		return
+	}
 	header := fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/json\r\n\r\n", len(body))
 	w.WriteString(header)
 	w.Write(body)
 	w.Flush()
 }
 
+func readMessage(r *bufio.Reader, framing string) ([]byte, error) {
+	if framing == "ndjson" {
+		return readLine(r)
+	}
+	return readFrame(r)
+}
+
+// readLine membaca satu pesan NDJSON, baris kosong dilewati.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
 func readFrame(r *bufio.Reader) ([]byte, error) {
 	var contentLength int
 	for {
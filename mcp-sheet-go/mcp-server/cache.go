@@ -0,0 +1,279 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- Sheet cache ----------
+// The sheets are cached by URL for SHEET_CACHE_TTL (default 1m). An expired
+// copy is revalidated with ETag and Last-Modified, so an unchanged sheet is
+// not downloaded again. With SHEET_CACHE_DIR the copies are also kept on disk
+// and survive restarts.
+
+// maxSheetSize caps the download of a sheet.
+const maxSheetSize = 32 << 20
+
+// maxSheetRedirects caps the redirects followed to download a sheet.
+const maxSheetRedirects = 10
+
+// Cache statuses.
+const (
+	cacheHit         = "hit"         // served from the cache without a request
+	cacheMiss        = "miss"        // not cached, downloaded
+	cacheRevalidated = "revalidated" // expired, the sheet did not change
+	cacheUpdated     = "updated"     // expired, the sheet changed
+	cacheRefreshed   = "refreshed"   // downloaded on request
+	cacheStale       = "stale"       // expired, revalidating failed
+)
+
+// CacheInfo tells how fresh the products of the sheet are.
+type CacheInfo struct {
+	Status string `json:"status"`
+	// FetchedAt is when the sheet was last downloaded or confirmed unchanged.
+	FetchedAt  time.Time `json:"fetched_at"`
+	AgeSeconds int       `json:"age_seconds"`
+	TTLSeconds int       `json:"ttl_seconds"`
+	// Error is why a stale copy was served.
+	Error string `json:"error,omitempty"`
+}
+
+type cachedSheet struct {
+	mu           sync.Mutex
+	body         []byte
+	contentType  string
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+}
+
+// cacheMeta is what the disk keeps next to the body of a sheet.
+type cacheMeta struct {
+	URL          string    `json:"url"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+type sheetCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedSheet
+	client  *http.Client
+}
+
+var sheets = &sheetCache{
+	entries: map[string]*cachedSheet{},
+	client:  &http.Client{Timeout: 20 * time.Second, CheckRedirect: checkSheetRedirect},
+}
+
+// checkSheetRedirect holds every redirect to the hosts sheet URLs may point
+// to, so an allowed host cannot send the server to another one.
+func checkSheetRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxSheetRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxSheetRedirects)
+	}
+	if !allowedSheetURL(req.URL.String(), req.URL) {
+		return fmt.Errorf("redirect to host %q is not allowed (SHEET_URL_HOSTS env)", req.URL.Hostname())
+	}
+	return nil
+}
+
+// sheetCacheTTL is how long a sheet is served without asking the server, 0
+// revalidates on every read.
+func sheetCacheTTL() time.Duration {
+	if v := os.Getenv("SHEET_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return time.Minute
+}
+
+// get returns the content of the sheet and its Content-Type. Reads of the
+// same sheet wait for each other, so concurrent sessions download it once.
+func (c *sheetCache) get(sheetURL string, refresh bool) ([]byte, string, *CacheInfo, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[sheetURL]
+	if !ok {
+		entry = &cachedSheet{}
+		c.entries[sheetURL] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.body == nil {
+		loadCachedSheet(sheetURL, entry)
+	}
+
+	ttl := sheetCacheTTL()
+	status := cacheMiss
+	switch {
+	case refresh:
+		status = cacheRefreshed
+	case entry.body != nil && time.Since(entry.fetchedAt) < ttl:
+		return entry.body, entry.contentType, entry.info(cacheHit, ttl, nil), nil
+	case entry.body != nil:
+		status = cacheUpdated
+	}
+
+	notModified, err := c.download(sheetURL, entry, !refresh)
+	switch {
+	case err != nil && entry.body != nil && !refresh:
+		return entry.body, entry.contentType, entry.info(cacheStale, ttl, err), nil
+	case err != nil:
+		return nil, "", nil, err
+	case notModified:
+		status = cacheRevalidated
+	}
+	saveCachedSheet(sheetURL, entry)
+	return entry.body, entry.contentType, entry.info(status, ttl, nil), nil
+}
+
+// download fetches the sheet into entry. With conditional it sends the
+// validators of entry and reports whether the server answered 304.
+func (c *sheetCache) download(sheetURL string, entry *cachedSheet, conditional bool) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, sheetURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("http get failed: %w", err)
+	}
+	if conditional && entry.body != nil {
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("http get failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry.body != nil {
+		entry.fetchedAt = time.Now()
+		return true, nil
+	}
+	if resp.StatusCode != 200 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return false, fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSheetSize+1))
+	if err != nil {
+		return false, fmt.Errorf("read body: %w", err)
+	}
+	if len(body) > maxSheetSize {
+		return false, fmt.Errorf("sheet is larger than %d MB", maxSheetSize>>20)
+	}
+	entry.body = body
+	entry.contentType = resp.Header.Get("Content-Type")
+	entry.etag = resp.Header.Get("ETag")
+	entry.lastModified = resp.Header.Get("Last-Modified")
+	entry.fetchedAt = time.Now()
+	return false, nil
+}
+
+func (e *cachedSheet) info(status string, ttl time.Duration, err error) *CacheInfo {
+	info := &CacheInfo{
+		Status:     status,
+		FetchedAt:  e.fetchedAt.UTC().Truncate(time.Second),
+		AgeSeconds: int(time.Since(e.fetchedAt).Seconds()),
+		TTLSeconds: int(ttl.Seconds()),
+	}
+	if err != nil {
+		info.Error = err.Error()
+	}
+	return info
+}
+
+// cachePaths returns where the disk keeps the body and the metadata of a
+// sheet, or false without SHEET_CACHE_DIR.
+func cachePaths(sheetURL string) (string, string, bool) {
+	dir := os.Getenv("SHEET_CACHE_DIR")
+	if dir == "" {
+		return "", "", false
+	}
+	sum := sha256.Sum256([]byte(sheetURL))
+	name := filepath.Join(dir, hex.EncodeToString(sum[:16]))
+	return name + ".csv", name + ".json", true
+}
+
+// loadCachedSheet fills entry from the disk, if it has the sheet.
+func loadCachedSheet(sheetURL string, entry *cachedSheet) {
+	bodyPath, metaPath, ok := cachePaths(sheetURL)
+	if !ok {
+		return
+	}
+	blob, err := os.ReadFile(metaPath)
+	if err != nil {
+		return
+	}
+	var meta cacheMeta
+	if err := json.Unmarshal(blob, &meta); err != nil || meta.URL != sheetURL {
+		return
+	}
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return
+	}
+	entry.body = body
+	entry.contentType = meta.ContentType
+	entry.etag = meta.ETag
+	entry.lastModified = meta.LastModified
+	entry.fetchedAt = meta.FetchedAt
+}
+
+// saveCachedSheet writes entry to the disk. A failed write only costs a
+// download after a restart, so it is logged and otherwise ignored.
+func saveCachedSheet(sheetURL string, entry *cachedSheet) {
+	bodyPath, metaPath, ok := cachePaths(sheetURL)
+	if !ok {
+		return
+	}
+	meta, _ := json.Marshal(cacheMeta{
+		URL:          sheetURL,
+		ContentType:  entry.contentType,
+		ETag:         entry.etag,
+		LastModified: entry.lastModified,
+		FetchedAt:    entry.fetchedAt,
+	})
+	err := os.MkdirAll(filepath.Dir(bodyPath), 0o755)
+	if err == nil {
+		err = writeFileAtomic(bodyPath, entry.body)
+	}
+	if err == nil {
+		err = writeFileAtomic(metaPath, meta)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sheet cache:", err)
+	}
+}
+
+// writeFileAtomic writes to a temp file and renames it over path, so readers
+// never see half a file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
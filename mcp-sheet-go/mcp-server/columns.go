@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ---------- Columns ----------
+// The columns of a sheet are found by their header, in English or
+// Indonesian ("Nama Produk", "Harga", "Kode", ...). Headers the aliases do
+// not know are mapped with the columns argument or SHEET_COLUMNS env, e.g.
+// "name=Menu,price=Harga Satuan". Only name and price are required: the
+// currency defaults to IDR and without an id column the id is made from the
+// name.
+
+// Product fields a column can hold.
+const (
+	fieldUserID   = "user_id"
+	fieldID       = "id"
+	fieldName     = "name"
+	fieldPrice    = "price"
+	fieldCurrency = "currency"
+	fieldImageURL = "image_url"
+)
+
+// fieldAliases are the normalized headers of every field.
+var fieldAliases = map[string][]string{
+	fieldUserID: {
+		"user id", "user", "userid", "phone", "phone number", "whatsapp", "wa",
+		"no hp", "nomor hp", "hp", "no wa", "nomor wa", "nomor whatsapp", "telepon", "telp", "no telp",
+	},
+	fieldID: {
+		"id", "product id", "item id", "sku", "code", "product code",
+		"kode", "kode produk", "kode barang", "id produk",
+	},
+	fieldName: {
+		"name", "product name", "product", "item", "item name", "title",
+		"nama", "nama produk", "produk", "nama barang", "barang", "menu", "nama menu",
+	},
+	fieldPrice: {
+		"price", "unit price", "price idr", "price rp",
+		"harga", "harga jual", "harga satuan", "harga rp", "harga idr",
+	},
+	fieldCurrency: {
+		"currency", "ccy", "mata uang",
+	},
+	fieldImageURL: {
+		"image url", "image", "image link", "photo", "picture", "imageurl",
+		"gambar", "foto", "url gambar", "link gambar", "foto produk", "gambar produk", "link foto",
+	},
+}
+
+var allFields = []string{fieldUserID, fieldID, fieldName, fieldPrice, fieldCurrency, fieldImageURL}
+
+// columnIndex is the column of every field, -1 when the sheet has none.
+type columnIndex map[string]int
+
+// normalizeHeader lower cases a header and reduces everything but letters
+// and digits to single spaces, so "Harga (Rp)" and "harga_rp" match.
+func normalizeHeader(h string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(h), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// resolveColumns finds the column of every field. columns maps fields to
+// headers and wins over the aliases.
+func resolveColumns(header []string, columns map[string]string) (columnIndex, error) {
+	normalized := make([]string, len(header))
+	for i, h := range header {
+		normalized[i] = normalizeHeader(h)
+	}
+	find := func(name string) int {
+		for i, h := range normalized {
+			if h == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	idx := columnIndex{}
+	for _, field := range allFields {
+		idx[field] = -1
+		if h, ok := columns[field]; ok {
+			if idx[field] = find(normalizeHeader(h)); idx[field] < 0 {
+				return nil, fmt.Errorf("column %q mapped to %s is not in the header", h, field)
+			}
+			continue
+		}
+		for _, alias := range fieldAliases[field] {
+			if i := find(alias); i >= 0 {
+				idx[field] = i
+				break
+			}
+		}
+	}
+
+	if idx[fieldName] < 0 || idx[fieldPrice] < 0 {
+		return nil, fmt.Errorf("missing required headers: need name and price (e.g. \"Nama Produk\" and \"Harga\"), or map them with columns")
+	}
+	return idx, nil
+}
+
+// parseColumns reads a mapping written as "field=Header,field=Header".
+func parseColumns(s string) (map[string]string, error) {
+	columns := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, header, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("column mapping %q must be field=Header", pair)
+		}
+		columns[strings.TrimSpace(field)] = strings.TrimSpace(header)
+	}
+	return columns, checkColumns(columns)
+}
+
+func checkColumns(columns map[string]string) error {
+	for field, header := range columns {
+		if _, ok := fieldAliases[field]; !ok {
+			return fmt.Errorf("unknown column field %q (fields: %s)", field, strings.Join(allFields, ", "))
+		}
+		if strings.TrimSpace(header) == "" {
+			return fmt.Errorf("column of %s is empty", field)
+		}
+	}
+	return nil
+}
+
+// sheetColumns merges the columns argument over SHEET_COLUMNS env.
+func sheetColumns(arg map[string]string) (map[string]string, error) {
+	columns := map[string]string{}
+	if v := os.Getenv("SHEET_COLUMNS"); v != "" {
+		env, err := parseColumns(v)
+		if err != nil {
+			return nil, fmt.Errorf("SHEET_COLUMNS: %w", err)
+		}
+		columns = env
+	}
+	if err := checkColumns(arg); err != nil {
+		return nil, err
+	}
+	for field, header := range arg {
+		columns[field] = header
+	}
+	return columns, nil
+}
+
+var thousandsPattern = regexp.MustCompile(`^\d{1,3}([.,]\d{3})+$`)
+
+// parsePrice reads prices as merchants write them: "15000", "15.000",
+// "Rp 15.000,00", "15,000.50", "20rb", "1,5jt".
+func parsePrice(s string) (float64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	for _, prefix := range []string{"rp.", "rp", "idr"} {
+		if strings.HasPrefix(v, prefix) {
+			v = strings.TrimSpace(v[len(prefix):])
+			break
+		}
+	}
+	multiplier := 1.0
+	for _, suffix := range []struct {
+		text string
+		mul  float64
+	}{{"ribu", 1e3}, {"rb", 1e3}, {"k", 1e3}, {"juta", 1e6}, {"jt", 1e6}} {
+		if strings.HasSuffix(v, suffix.text) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, suffix.text))
+			multiplier = suffix.mul
+			break
+		}
+	}
+	v = strings.ReplaceAll(v, " ", "")
+
+	dot, comma := strings.LastIndex(v, "."), strings.LastIndex(v, ",")
+	switch {
+	case dot >= 0 && comma >= 0:
+		// The last separator is the decimal one.
+		if dot > comma {
+			v = strings.ReplaceAll(v, ",", "")
+		} else {
+			v = strings.ReplaceAll(strings.ReplaceAll(v, ".", ""), ",", ".")
+		}
+	case thousandsPattern.MatchString(v):
+		v = strings.NewReplacer(".", "", ",", "").Replace(v)
+	case comma >= 0:
+		v = strings.ReplaceAll(v, ",", ".")
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	// ParseFloat reads "nan" and "inf", which JSON cannot hold.
+	if err != nil || price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return price * multiplier, nil
+}
+
+// nameID makes the id of a product from its name, for sheets without an id
+// column. taken holds the ids given so far and gets the new one.
+func nameID(name string, taken map[string]bool) string {
+	base := strings.ReplaceAll(normalizeHeader(name), " ", "-")
+	if base == "" {
+		base = "product"
+	}
+	id := base
+	for n := 2; taken[id]; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	taken[id] = true
+	return id
+}
+
+// columnsSchema is the JSON schema of the columns tool argument.
+func columnsSchema() map[string]any {
+	props := map[string]any{}
+	for _, field := range allFields {
+		props[field] = map[string]any{"type": "string"}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+		"description":          "Header of the column of each field, for headers the English and Indonesian aliases do not cover. Defaults to SHEET_COLUMNS env.",
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{in: "15000", want: 15000},
+		{in: "15.000", want: 15000},
+		{in: "1.250.000", want: 1250000},
+		{in: "Rp 15.000,00", want: 15000},
+		{in: "Rp. 7.500", want: 7500},
+		{in: "IDR 20,000", want: 20000},
+		{in: "15,000.50", want: 15000.5},
+		{in: "12,5", want: 12.5},
+		{in: "1,5jt", want: 1500000},
+		{in: "2 juta", want: 2000000},
+		{in: "20rb", want: 20000},
+		{in: "25 ribu", want: 25000},
+		{in: "20k", want: 20000},
+		{in: "0", want: 0},
+		{in: "nan", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "inf", wantErr: true},
+		{in: "-5000", wantErr: true},
+		{in: "gratis", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parsePrice(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePrice(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parsePrice(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		columns map[string]string
+		want    columnIndex
+		wantErr bool
+	}{
+		{
+			name:   "english",
+			header: []string{"user_id", "id", "name", "price", "currency", "image_url"},
+			want:   columnIndex{fieldUserID: 0, fieldID: 1, fieldName: 2, fieldPrice: 3, fieldCurrency: 4, fieldImageURL: 5},
+		},
+		{
+			name:   "indonesian",
+			header: []string{"No HP", "Kode Produk", "Nama Produk", "Harga (Rp)", "Foto"},
+			want:   columnIndex{fieldUserID: 0, fieldID: 1, fieldName: 2, fieldPrice: 3, fieldCurrency: -1, fieldImageURL: 4},
+		},
+		{
+			name:   "name and price only",
+			header: []string{" Menu ", "HARGA_JUAL"},
+			want:   columnIndex{fieldUserID: -1, fieldID: -1, fieldName: 0, fieldPrice: 1, fieldCurrency: -1, fieldImageURL: -1},
+		},
+		{
+			name:    "mapping wins over aliases",
+			header:  []string{"Judul", "Nominal", "Nama"},
+			columns: map[string]string{fieldName: "judul", fieldPrice: "Nominal"},
+			want:    columnIndex{fieldUserID: -1, fieldID: -1, fieldName: 0, fieldPrice: 1, fieldCurrency: -1, fieldImageURL: -1},
+		},
+		{
+			name:    "missing price",
+			header:  []string{"id", "name"},
+			wantErr: true,
+		},
+		{
+			name:    "mapped header missing",
+			header:  []string{"name", "price"},
+			columns: map[string]string{fieldID: "SKU Internal"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveColumns(tt.header, tt.columns)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveColumns() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveColumns() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameID(t *testing.T) {
+	taken := map[string]bool{}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Kopi Susu", "kopi-susu"},
+		{"Kopi Susu", "kopi-susu-2"},
+		{"kopi  susu!", "kopi-susu-3"},
+		{"Es Teh (Jumbo)", "es-teh-jumbo"},
+		{"!!!", "product"},
+		{"???", "product-2"},
+	}
+	for _, tt := range tests {
+		if got := nameID(tt.name, taken); got != tt.want {
+			t.Errorf("nameID(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
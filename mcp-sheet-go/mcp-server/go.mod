@@ -1,3 +1,19 @@
 module mcp-sheet-go
 
 go 1.25.1
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.32
+	github.com/xuri/excelize/v2 v2.9.1
+)
+
+require (
+	github.com/richardlehane/mscfb v1.0.4 // indirect
+	github.com/richardlehane/msoleps v1.0.4 // indirect
+	github.com/tiendc/go-deepcopy v1.6.0 // indirect
+	github.com/xuri/efp v0.0.1 // indirect
+	github.com/xuri/nfp v0.0.1 // indirect
+	golang.org/x/crypto v0.38.0 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/text v0.25.0 // indirect
+)
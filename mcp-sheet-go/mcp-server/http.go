@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ---------- Streamable HTTP ----------
+// The http transport serves the MCP streamable HTTP transport on mcpPath:
+// POST sends messages, GET opens an SSE stream for server messages and DELETE
+// ends the session. A session starts with initialize and is identified by the
+// Mcp-Session-Id header afterwards.
+//
+// Remote clients get less than the local stdio client:
+//
+//   - requests need the MCP_HTTP_TOKEN bearer token, which is required unless
+//     -addr is a loopback address
+//   - the write tools need MCP_HTTP_WRITE_TOOLS=true
+//   - sheet URLs they pass, and every redirect they lead to, must be on a
+//     host of SHEET_URL_HOSTS or its subdomains (docs.google.com and
+//     googleusercontent.com, where Google redirects exports, by default, *
+//     for any), so the server cannot be used to reach internal addresses
+
+const (
+	mcpPath        = "/mcp"
+	sessionHeader  = "Mcp-Session-Id"
+	maxBodyBytes   = 10 << 20
+	sessionIdleTTL = 30 * time.Minute
+	sseKeepAlive   = 25 * time.Second
+	// outboxSize is how many server messages wait for the GET stream of a
+	// session, later ones are dropped.
+	outboxSize      = 64
+	shutdownTimeout = 10 * time.Second
+)
+
+type session struct {
+	id        string
+	sub       *subscriber
+	outbox    chan JSONRPCNotification
+	done      chan struct{}
+	closeOnce sync.Once
+	mu        sync.Mutex
+	lastSeen  time.Time
+}
+
+func (s *session) touch() {
+	s.mu.Lock()
+	s.lastSeen = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *session) idleSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSeen
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		s.sub.remove()
+		close(s.done)
+	})
+}
+
+var (
+	// writeToolsEnabled is false when the http transport serves remote
+	// clients without MCP_HTTP_WRITE_TOOLS.
+	writeToolsEnabled = true
+	// sheetURLHosts are the hosts client sheet URLs may point to, with their
+	// subdomains. nil allows any host.
+	sheetURLHosts []string
+)
+
+type httpServer struct {
+	// token is the bearer token of every request, empty for none.
+	token    string
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func serveHTTP(addr string) error {
+	token, err := configureHTTP(addr)
+	if err != nil {
+		return err
+	}
+	h := &httpServer{token: token, sessions: map[string]*session{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(mcpPath, h.handle)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// Open SSE streams would hold Shutdown until its timeout otherwise.
+	srv.RegisterOnShutdown(h.closeAll)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go h.expireSessions(ctx)
+	go watchSheet(ctx)
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "listening on http://%s%s\n", addr, mcpPath)
+		errCh <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// configureHTTP reads the limits of remote clients and returns the token.
+func configureHTTP(addr string) (string, error) {
+	token := os.Getenv("MCP_HTTP_TOKEN")
+	if token == "" && !loopbackAddr(addr) {
+		return "", fmt.Errorf("MCP_HTTP_TOKEN env is required when -addr %s is not a loopback address", addr)
+	}
+
+	writeToolsEnabled = false
+	if v := os.Getenv("MCP_HTTP_WRITE_TOOLS"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", fmt.Errorf("MCP_HTTP_WRITE_TOOLS: %w", err)
+		}
+		writeToolsEnabled = enabled
+	}
+
+	hosts := os.Getenv("SHEET_URL_HOSTS")
+	if hosts == "" {
+		hosts = "docs.google.com,googleusercontent.com"
+	}
+	sheetURLHosts = []string{}
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host == "*" {
+			sheetURLHosts = nil
+			break
+		} else if host != "" {
+			sheetURLHosts = append(sheetURLHosts, host)
+		}
+	}
+	return token, nil
+}
+
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return host == "localhost" || net.ParseIP(host).IsLoopback()
+}
+
+// allowedSheetURL reports whether a client may have the sheet at u read. The
+// SHEET_URL env is set by the operator and always allowed.
+func allowedSheetURL(rawURL string, u *url.URL) bool {
+	if sheetURLHosts == nil || strings.TrimSpace(rawURL) == strings.TrimSpace(os.Getenv("SHEET_URL")) {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	return slices.ContainsFunc(sheetURLHosts, func(allowed string) bool {
+		return host == allowed || strings.HasSuffix(host, "."+allowed)
+	})
+}
+
+func (h *httpServer) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) == 1
+}
+
+func (h *httpServer) handle(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+		http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+	if !allowedOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodGet:
+		h.handleGet(w, r)
+	case http.MethodDelete:
+		sess, ok := h.session(w, r)
+		if !ok {
+			return
+		}
+		h.endSession(sess)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *httpServer) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	messages, batch, err := splitBatch(body)
+	if err != nil {
+		writeHTTPJSON(w, http.StatusBadRequest, "", errorResponse(nil, -32700, "Parse error (invalid JSON)", err.Error()))
+		return
+	}
+
+	// Only initialize may come without a session, and it starts a new one.
+	var sess *session
+	if !batch && isInitialize(messages[0]) {
+		sess = h.newSession()
+	} else if sess, _ = h.session(w, r); sess == nil {
+		return
+	}
+
+	var responses []*JSONRPCResponse
+	for _, msg := range messages {
+		if resp := handleMessage(sess.sub, msg); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		// Notifications and responses only.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	var out any = responses[0]
+	if batch {
+		out = responses
+	}
+	if acceptsOnlySSE(r) {
+		writeSSE(w, sess.id, out)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, sess.id, out)
+}
+
+// handleGet streams the messages the server sends on its own, the resource
+// notifications, until the session ends or the client leaves.
+func (h *httpServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "GET needs Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	sess, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(sessionHeader, sess.id)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sess.done:
+			return
+		case n := <-sess.outbox:
+			b, _ := json.Marshal(n)
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			sess.touch()
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// session returns the session of the request, or writes the error response
+// when the header is missing or the session is unknown.
+func (h *httpServer) session(w http.ResponseWriter, r *http.Request) (*session, bool) {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, sessionHeader+" header is required", http.StatusBadRequest)
+		return nil, false
+	}
+	h.mu.Lock()
+	sess, ok := h.sessions[id]
+	h.mu.Unlock()
+	if !ok {
+		// 404 tells the client to initialize a new session.
+		http.Error(w, "session not found", http.StatusNotFound)
+		return nil, false
+	}
+	sess.touch()
+	return sess, true
+}
+
+func (h *httpServer) newSession() *session {
+	b := make([]byte, 16)
+	rand.Read(b)
+	sess := &session{
+		id:       hex.EncodeToString(b),
+		outbox:   make(chan JSONRPCNotification, outboxSize),
+		done:     make(chan struct{}),
+		lastSeen: time.Now(),
+	}
+	sess.sub = newSubscriber(func(n JSONRPCNotification) {
+		select {
+		case sess.outbox <- n:
+		default:
+		}
+	})
+	h.mu.Lock()
+	h.sessions[sess.id] = sess
+	h.mu.Unlock()
+	return sess
+}
+
+func (h *httpServer) endSession(sess *session) {
+	h.mu.Lock()
+	delete(h.sessions, sess.id)
+	h.mu.Unlock()
+	sess.close()
+}
+
+func (h *httpServer) closeAll() {
+	h.mu.Lock()
+	sessions := h.sessions
+	h.sessions = map[string]*session{}
+	h.mu.Unlock()
+	for _, sess := range sessions {
+		sess.close()
+	}
+}
+
+// expireSessions ends the sessions idle for longer than sessionIdleTTL.
+func (h *httpServer) expireSessions(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.mu.Lock()
+			var idle []*session
+			for _, sess := range h.sessions {
+				if now.Sub(sess.idleSince()) > sessionIdleTTL {
+					idle = append(idle, sess)
+				}
+			}
+			h.mu.Unlock()
+			for _, sess := range idle {
+				h.endSession(sess)
+			}
+		}
+	}
+}
+
+// splitBatch returns the messages of a body holding one message or a batch.
+func splitBatch(body []byte) ([]json.RawMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var messages []json.RawMessage
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return nil, true, err
+		}
+		if len(messages) == 0 {
+			return nil, true, errors.New("empty batch")
+		}
+		return messages, true, nil
+	}
+	if !json.Valid(body) {
+		return nil, false, errors.New("invalid JSON")
+	}
+	return []json.RawMessage{body}, false, nil
+}
+
+func isInitialize(msg json.RawMessage) bool {
+	var req JSONRPCRequest
+	return json.Unmarshal(msg, &req) == nil && req.Method == "initialize"
+}
+
+// acceptsOnlySSE reports whether the client cannot take a plain JSON
+// response. Clients accepting both get JSON, since every response is ready at
+// once.
+func acceptsOnlySSE(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/event-stream") &&
+		!strings.Contains(accept, "application/json") &&
+		!strings.Contains(accept, "*/*")
+}
+
+func writeHTTPJSON(w http.ResponseWriter, status int, sessionID string, v any) {
+	if sessionID != "" {
+		w.Header().Set(sessionHeader, sessionID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeSSE(w http.ResponseWriter, sessionID string, v any) {
+	b, _ := json.Marshal(v)
+	w.Header().Set(sessionHeader, sessionID)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", b)
+}
+
+// allowedOrigin rejects browser requests from other sites, which could
+// otherwise reach a server on localhost through DNS rebinding.
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" || net.ParseIP(host).IsLoopback() {
+		return true
+	}
+	reqHost, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		reqHost = r.Host
+	}
+	return strings.EqualFold(host, reqHost)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSplitBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCount int
+		wantBatch bool
+		wantErr   bool
+	}{
+		{name: "single", body: `{"jsonrpc":"2.0","id":1,"method":"ping"}`, wantCount: 1},
+		{name: "single with spaces", body: " \n{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}\n", wantCount: 1},
+		{name: "batch", body: `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`, wantCount: 2, wantBatch: true},
+		{name: "empty batch", body: `[]`, wantBatch: true, wantErr: true},
+		{name: "broken batch", body: `[{"jsonrpc":"2.0"`, wantBatch: true, wantErr: true},
+		{name: "invalid", body: `{"jsonrpc":`, wantErr: true},
+		{name: "empty", body: ``, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, batch, err := splitBatch([]byte(tt.body))
+			if (err != nil) != tt.wantErr || batch != tt.wantBatch || len(messages) != tt.wantCount {
+				t.Errorf("splitBatch() = %d messages, batch %v, error %v, want %d, %v, error %v",
+					len(messages), batch, err, tt.wantCount, tt.wantBatch, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsInitialize(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`, true},
+		{`{"jsonrpc":"2.0","method":"notifications/initialized"}`, false},
+		{`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, false},
+		{`{"jsonrpc":"2.0","id":1,"result":{}}`, false},
+		{`[{"jsonrpc":"2.0","id":1,"method":"initialize"}]`, false},
+	}
+	for _, tt := range tests {
+		if got := isInitialize(json.RawMessage(tt.msg)); got != tt.want {
+			t.Errorf("isInitialize(%s) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedSheetURL(t *testing.T) {
+	defer func(hosts []string) { sheetURLHosts = hosts }(sheetURLHosts)
+	sheetURLHosts = []string{"docs.google.com", "googleusercontent.com"}
+	t.Setenv("SHEET_URL", "http://10.0.0.5/catalog.csv")
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://docs.google.com/spreadsheets/d/abc/export?format=csv", true},
+		{"https://DOCS.google.com/spreadsheets/d/abc", true},
+		{"https://doc-0g-sheets.googleusercontent.com/export/abc", true},
+		{"https://evilgoogleusercontent.com/x.csv", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://10.0.0.5/catalog.csv", true}, // SHEET_URL
+		{"http://10.0.0.5/other.csv", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := allowedSheetURL(tt.url, u); got != tt.want {
+			t.Errorf("allowedSheetURL(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSheetDownloadChecksRedirects(t *testing.T) {
+	defer func(hosts []string) { sheetURLHosts = hosts }(sheetURLHosts)
+
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "name,price\nsecret,1\n")
+	}))
+	defer internal.Close()
+	internalURL, _ := url.Parse(internal.URL)
+
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the internal server is reached through localhost, a host that is
+		// not allowed
+		http.Redirect(w, r, "http://localhost:"+internalURL.Port()+"/", http.StatusFound)
+	}))
+	defer allowed.Close()
+
+	sheetURLHosts = []string{"127.0.0.1"}
+	_, err := sheets.download(allowed.URL+"/sheet.csv", &cachedSheet{}, false)
+	if err == nil || !strings.Contains(err.Error(), `redirect to host "localhost" is not allowed`) {
+		t.Errorf("download through a redirect to another host returned %v", err)
+	}
+
+	sheetURLHosts = []string{"127.0.0.1", "localhost"}
+	entry := &cachedSheet{}
+	if _, err := sheets.download(allowed.URL+"/sheet.csv", entry, false); err != nil || !strings.Contains(string(entry.body), "secret") {
+		t.Errorf("download through an allowed redirect = %q, %v", entry.body, err)
+	}
+}
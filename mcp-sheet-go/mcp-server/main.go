@@ -3,15 +3,17 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"encoding/csv"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 )
 
 // ---------- JSON-RPC ----------
@@ -27,6 +29,11 @@ type JSONRPCResponse struct {
 	Result  any       `json:"result,omitempty"`
 	Error   *RPCError `json:"error,omitempty"`
 }
+type JSONRPCNotification struct {
+	Jsonrpc string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
 type RPCError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -73,86 +80,348 @@ type Product struct {
 }
 type FetchProductsInput struct {
 	SheetURL string `json:"sheet_url,omitempty"`
+	Source   string `json:"source,omitempty"` // "sheet" or "store"
 	Limit    int    `json:"limit,omitempty"`
 	Offset   int    `json:"offset,omitempty"`
 	UserID   string `json:"user_id,omitempty"` // optional filter
+	Refresh  bool   `json:"refresh,omitempty"` // bypass the sheet cache
+	// Columns maps fields to the headers of the sheet.
+	Columns map[string]string `json:"columns,omitempty"`
+}
+
+// ProductInput is the product of the write tools. Fields left out keep their
+// value on update_product.
+type ProductInput struct {
+	UserID   string   `json:"user_id,omitempty"`
+	ID       string   `json:"id"`
+	Name     *string  `json:"name,omitempty"`
+	Price    *float64 `json:"price,omitempty"`
+	Currency *string  `json:"currency,omitempty"`
+	ImageURL *string  `json:"image_url,omitempty"`
+}
+type DeleteProductInput struct {
+	UserID string `json:"user_id,omitempty"`
+	ID     string `json:"id"`
+}
+type UpsertProductsInput struct {
+	Products []ProductInput `json:"products"`
+}
+type UpsertProductsOutput struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
 }
 type FetchProductsOutput struct {
 	Items      []Product `json:"items"`
 	Total      int       `json:"total"`
 	NextOffset *int      `json:"next_offset,omitempty"`
+	// Skipped lists rows that could not be parsed and Warnings rows kept with
+	// a cell ignored. Only the first page has them.
+	Skipped  []RowIssue `json:"skipped,omitempty"`
+	Warnings []RowIssue `json:"warnings,omitempty"`
+	// Cache tells how fresh the sheet is, the store has none.
+	Cache *CacheInfo `json:"cache,omitempty"`
+}
+
+// Severities of a row issue.
+const (
+	issueError   = "error"   // the row is left out of the products
+	issueWarning = "warning" // the row is kept without the cell
+)
+
+// RowIssue is a problem found in a sheet row: the cell and why.
+type RowIssue struct {
+	Row      int    `json:"row"` // sheet row number, the header is row 1
+	UserID   string `json:"user_id,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Column   string `json:"column,omitempty"` // header of the cell
+	Value    string `json:"value,omitempty"`  // raw cell value
+	Reason   string `json:"reason"`
+	Severity string `json:"severity"`
+}
+
+// splitIssues separates the skipped rows from the warnings.
+func splitIssues(issues []RowIssue) (skipped, warnings []RowIssue) {
+	for _, issue := range issues {
+		if issue.Severity == issueError {
+			skipped = append(skipped, issue)
+		} else {
+			warnings = append(warnings, issue)
+		}
+	}
+	return skipped, warnings
 }
 
+// Sources of the products.
+const (
+	sourceSheet = "sheet"
+	sourceStore = "store"
+)
+
+// writeToolNames are the tools that change the store.
+var writeToolNames = []string{"add_product", "update_product", "delete_product", "upsert_products"}
+
+// maxUpsertProducts caps the products of one upsert_products call.
+const maxUpsertProducts = 1000
+
+// store is nil when STORE_TYPE is not set, the write tools then fail.
+var store Store
+
 // ---------- main loop ----------
 func main() {
-	reader := bufio.NewReader(os.Stdin)
+	transport := flag.String("transport", "stdio", "stdio or http")
+	addr := flag.String("addr", "127.0.0.1:8080", "listen address of the http transport")
+	framing := flag.String("framing", framingAuto, "stdio framing: auto, ndjson or content-length")
+	flag.Parse()
+
+	var err error
+	store, err = openStore()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "store:", err)
+		os.Exit(1)
+	}
+	if _, err := sheetColumns(nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch *transport {
+	case "stdio":
+		if *framing != framingAuto && *framing != framingNDJSON && *framing != framingContentLength {
+			fmt.Fprintf(os.Stderr, "unknown framing %q (auto, ndjson or content-length)\n", *framing)
+			os.Exit(2)
+		}
+		go watchSheet(context.Background())
+		serveStdio(&stdioConn{r: bufio.NewReader(os.Stdin), w: os.Stdout, framing: *framing})
+	case "http":
+		if err := serveHTTP(*addr); err != nil {
+			fmt.Fprintln(os.Stderr, "http:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown transport %q (stdio or http)\n", *transport)
+		os.Exit(2)
+	}
+}
+
+// serveStdio answers the messages of conn until its input is closed.
+func serveStdio(conn *stdioConn) {
+	sub := newSubscriber(func(n JSONRPCNotification) { conn.write(n) })
+	defer sub.remove()
 	for {
-		reqBytes, err := readFramedMessage(reader)
+		body, err := conn.read()
 		if err != nil {
-			if err == io.EOF {
-				return
-			}
 			return
 		}
-		var req JSONRPCRequest
-		if err := json.Unmarshal(reqBytes, &req); err != nil {
-			writeError(nil, -32700, "Parse error (invalid JSON)", err.Error())
+		messages, batch, err := splitBatch(body)
+		if err != nil {
+			conn.write(errorResponse(nil, -32700, "Parse error (invalid JSON)", err.Error()))
 			continue
 		}
-		switch req.Method {
-		case "initialize":
-			handleInitialize(req.ID)
-		case "tools/list":
-			handleToolsList(req.ID)
-		case "tools/call":
-			handleToolsCall(req.ID, req.Params)
+		var responses []*JSONRPCResponse
+		for _, msg := range messages {
+			if resp := handleMessage(sub, msg); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		switch {
+		case len(responses) == 0:
+		case batch:
+			conn.write(responses)
 		default:
-			writeError(req.ID, -32601, "Method not found", req.Method)
+			conn.write(responses[0])
 		}
 	}
 }
 
-func handleInitialize(id any) {
+// handleMessage dispatches one JSON-RPC message of sub. It returns nil for
+// notifications, which get no response.
+func handleMessage(sub *subscriber, reqBytes []byte) *JSONRPCResponse {
+	var req JSONRPCRequest
+	if err := json.Unmarshal(reqBytes, &req); err != nil {
+		return errorResponse(nil, -32700, "Parse error (invalid JSON)", err.Error())
+	}
+	if req.ID == nil && strings.HasPrefix(req.Method, "notifications/") {
+		return nil
+	}
+	switch req.Method {
+	case "initialize":
+		return handleInitialize(req.ID, req.Params)
+	case "ping":
+		return resultResponse(req.ID, struct{}{})
+	case "tools/list":
+		return handleToolsList(req.ID)
+	case "tools/call":
+		return handleToolsCall(req.ID, req.Params)
+	case "resources/list":
+		return handleResourcesList(req.ID, req.Params)
+	case "resources/templates/list":
+		return handleResourceTemplatesList(req.ID)
+	case "resources/read":
+		return handleResourcesRead(req.ID, req.Params)
+	case "resources/subscribe":
+		return handleResourcesSubscribe(sub, req.ID, req.Params, true)
+	case "resources/unsubscribe":
+		return handleResourcesSubscribe(sub, req.ID, req.Params, false)
+	case "prompts/list":
+		return handlePromptsList(req.ID)
+	case "prompts/get":
+		return handlePromptsGet(req.ID, req.Params)
+	default:
+		return errorResponse(req.ID, -32601, "Method not found", req.Method)
+	}
+}
+
+// protocolVersions are the MCP versions the server speaks, newest first.
+var protocolVersions = []string{"2025-03-26", "2024-11-05"}
+
+func handleInitialize(id any, params json.RawMessage) *JSONRPCResponse {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(params, &p)
+
 	var res InitializeResult
-	res.ProtocolVersion = "2024-11-05"
-	res.Capabilities = map[string]any{"tools": map[string]any{}}
+	// Older clients that do not say which version they want get the original
+	// one.
+	res.ProtocolVersion = protocolVersions[len(protocolVersions)-1]
+	for _, v := range protocolVersions {
+		if v == p.ProtocolVersion {
+			res.ProtocolVersion = v
+		}
+	}
+	res.Capabilities = map[string]any{
+		"tools":     map[string]any{},
+		"resources": map[string]any{"subscribe": true, "listChanged": true},
+		"prompts":   map[string]any{},
+	}
 	res.ServerInfo.Name = "post-mcp-server-go"
-	res.ServerInfo.Version = "0.1.1"
-	writeResult(id, res)
-}
-func handleToolsList(id any) {
-	tool := Tool{
-		Name:        "fetch_products",
-		Description: "Fetch products from a public Google Sheet CSV. Headers: id,name,price,currency (image_url,user_id optional).",
-		InputSchema: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"sheet_url": map[string]any{"type": "string", "description": "CSV export URL. If omitted, uses SHEET_URL env."},
-				"limit":     map[string]any{"type": "number", "description": "Max items (default 50)."},
-				"offset":    map[string]any{"type": "number", "description": "Offset (default 0)."},
-				"user_id":   map[string]any{"type": "string", "description": "Optional filter by owner user_id (phone)."},
+	res.ServerInfo.Version = "0.2.0"
+	return resultResponse(id, res)
+}
+func handleToolsList(id any) *JSONRPCResponse {
+	productProps := map[string]any{
+		"user_id":   map[string]any{"type": "string", "description": "Owner user_id (phone). Empty for single-merchant stores."},
+		"id":        map[string]any{"type": "string", "description": "Product id, unique per user_id."},
+		"name":      map[string]any{"type": "string"},
+		"price":     map[string]any{"type": "number", "minimum": 0},
+		"currency":  map[string]any{"type": "string", "description": "Default IDR."},
+		"image_url": map[string]any{"type": "string", "description": "http(s) URL, empty to remove."},
+	}
+	tools := []Tool{
+		{
+			Name:        "fetch_products",
+			Description: "Fetch products from a public Google Sheet CSV or from the local store. Columns are found by English or Indonesian headers (e.g. name/\"Nama Produk\", price/\"Harga\"), or mapped with columns; only name and price are required, ids are made from names when missing. Rows that cannot be parsed are listed in skipped, and rows kept with an ignored cell in warnings, on the first page; validate_sheet checks a sheet without fetching.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"sheet_url": map[string]any{"type": "string", "description": sheetURLDescription},
+					"refresh":   map[string]any{"type": "boolean", "description": "Download the sheet again instead of using the cache."},
+					"columns":   columnsSchema(),
+					"source":    map[string]any{"type": "string", "enum": []string{"sheet", "store"}, "description": "Where to read from. Defaults to the sheet, or the store when no sheet URL is set."},
+					"limit":     map[string]any{"type": "number", "description": "Max items (default 50)."},
+					"offset":    map[string]any{"type": "number", "description": "Offset (default 0)."},
+					"user_id":   map[string]any{"type": "string", "description": "Optional filter by owner user_id (phone)."},
+				},
+				"additionalProperties": false,
+			},
+		},
+		searchProductsTool,
+		validateSheetTool,
+		{
+			Name:        "add_product",
+			Description: "Add a product to the local store. Fails if the user_id and id already exist.",
+			InputSchema: map[string]any{
+				"type":                 "object",
+				"properties":           productProps,
+				"required":             []string{"id", "name", "price"},
+				"additionalProperties": false,
+			},
+		},
+		{
+			Name:        "update_product",
+			Description: "Update a product of the local store. Only the given fields change.",
+			InputSchema: map[string]any{
+				"type":                 "object",
+				"properties":           productProps,
+				"required":             []string{"id"},
+				"additionalProperties": false,
+			},
+		},
+		{
+			Name:        "delete_product",
+			Description: "Delete a product from the local store.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"user_id": productProps["user_id"],
+					"id":      productProps["id"],
+				},
+				"required":             []string{"id"},
+				"additionalProperties": false,
+			},
+		},
+		{
+			Name:        "upsert_products",
+			Description: fmt.Sprintf("Create or replace up to %d products of the local store at once. Every product needs id, name and price.", maxUpsertProducts),
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"products": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type":                 "object",
+							"properties":           productProps,
+							"required":             []string{"id", "name", "price"},
+							"additionalProperties": false,
+						},
+					},
+				},
+				"required":             []string{"products"},
+				"additionalProperties": false,
 			},
-			"additionalProperties": false,
 		},
 	}
-	writeResult(id, ToolsListResult{Tools: []Tool{tool}})
+	if !writeToolsEnabled {
+		tools = slices.DeleteFunc(tools, func(t Tool) bool { return slices.Contains(writeToolNames, t.Name) })
+	}
+	return resultResponse(id, ToolsListResult{Tools: tools})
 }
-func handleToolsCall(id any, params json.RawMessage) {
+func handleToolsCall(id any, params json.RawMessage) *JSONRPCResponse {
 	var p ToolsCallParams
 	if err := json.Unmarshal(params, &p); err != nil {
-		writeError(id, -32602, "Invalid params", err.Error())
-		return
+		return errorResponse(id, -32602, "Invalid params", err.Error())
 	}
-	if p.Name != "fetch_products" {
-		writeError(id, -32601, "Tool not found", p.Name)
-		return
-	}
-	var in FetchProductsInput
+	args := json.RawMessage("{}")
 	if p.Args != nil {
-		if err := json.Unmarshal(*p.Args, &in); err != nil {
-			writeError(id, -32602, "Invalid arguments", err.Error())
-			return
+		args = *p.Args
+	}
+	switch p.Name {
+	case "fetch_products":
+		return handleFetchProducts(id, args)
+	case "search_products":
+		return handleSearchProducts(id, args)
+	case "validate_sheet":
+		return handleValidateSheet(id, args)
+	case "add_product", "update_product", "delete_product", "upsert_products":
+		if !writeToolsEnabled {
+			return errorResponse(id, 1001, "CONFIG_ERROR", "write tools are disabled over http (MCP_HTTP_WRITE_TOOLS env)")
+		}
+		if store == nil {
+			return errorResponse(id, 1001, "CONFIG_ERROR", "store missing (STORE_TYPE and STORE_PATH env)")
 		}
+		return handleWriteTool(id, p.Name, args)
+	default:
+		return errorResponse(id, -32601, "Tool not found", p.Name)
+	}
+}
+
+func handleFetchProducts(id any, args json.RawMessage) *JSONRPCResponse {
+	var in FetchProductsInput
+	if err := json.Unmarshal(args, &in); err != nil {
+		return errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+	if err := checkColumns(in.Columns); err != nil {
+		return errorResponse(id, -32602, "Invalid arguments", err.Error())
 	}
 	if in.Limit <= 0 {
 		in.Limit = 50
@@ -166,78 +435,257 @@ func handleToolsCall(id any, params json.RawMessage) {
 	if in.SheetURL == "" {
 		in.SheetURL = os.Getenv("SHEET_URL")
 	}
-	if in.SheetURL == "" {
-		writeError(id, 1001, "CONFIG_ERROR", "sheet_url missing (arg or SHEET_URL env)")
-		return
+	if in.Source == "" {
+		in.Source = defaultSource(in.SheetURL)
 	}
 
-	out, err := fetchProducts(in)
-	if err != nil {
-		writeError(id, 1002, "FETCH_ERROR", err.Error())
-		return
+	var (
+		out FetchProductsOutput
+		err error
+	)
+	switch in.Source {
+	case sourceSheet:
+		if in.SheetURL == "" {
+			return errorResponse(id, 1001, "CONFIG_ERROR", "sheet_url missing (arg or SHEET_URL env)")
+		}
+		out, err = fetchProducts(in)
+		if err != nil {
+			return errorResponse(id, 1002, "FETCH_ERROR", err.Error())
+		}
+	case sourceStore:
+		if store == nil {
+			return errorResponse(id, 1001, "CONFIG_ERROR", "store missing (STORE_TYPE and STORE_PATH env)")
+		}
+		products, err := store.List(in.UserID)
+		if err != nil {
+			return errorResponse(id, 1003, "STORE_ERROR", err.Error())
+		}
+		out = paginate(products, in)
+	default:
+		return errorResponse(id, -32602, "Invalid arguments", fmt.Sprintf("source must be sheet or store, got %q", in.Source))
 	}
-	blob, _ := json.MarshalIndent(out, "", "  ")
-	writeResult(id, ToolsCallResult{
-		Content: []ToolContent{{Type: "text", Text: string(blob)}},
-	})
+	return jsonToolResult(id, out)
+}
+
+func handleWriteTool(id any, name string, args json.RawMessage) *JSONRPCResponse {
+	var (
+		result any
+		err    error
+		// changed are the products written, listChanged tells whether
+		// products were added or removed.
+		changed     []Product
+		listChanged bool
+	)
+	switch name {
+	case "add_product":
+		var in ProductInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+		var p Product
+		if p, err = in.toProduct(nil); err == nil {
+			err = store.Insert(p)
+			result = p
+			changed, listChanged = []Product{p}, true
+		}
+	case "update_product":
+		var in ProductInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+		var current *Product
+		if strings.TrimSpace(in.ID) == "" {
+			err = invalidInput("id is required")
+		} else if current, err = store.Get(strings.TrimSpace(in.UserID), strings.TrimSpace(in.ID)); err == nil {
+			var p Product
+			if p, err = in.toProduct(current); err == nil {
+				err = store.Update(p)
+				result = p
+				changed = []Product{p}
+			}
+		}
+	case "delete_product":
+		var in DeleteProductInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+		in.UserID, in.ID = strings.TrimSpace(in.UserID), strings.TrimSpace(in.ID)
+		if in.ID == "" {
+			err = invalidInput("id is required")
+		} else {
+			err = store.Delete(in.UserID, in.ID)
+			result = map[string]any{"deleted": true, "user_id": in.UserID, "id": in.ID}
+			changed, listChanged = []Product{{UserID: in.UserID, ID: in.ID}}, true
+		}
+	case "upsert_products":
+		var in UpsertProductsInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return errorResponse(id, -32602, "Invalid arguments", err.Error())
+		}
+		var products []Product
+		if products, err = in.toProducts(); err == nil {
+			var out UpsertProductsOutput
+			out.Created, out.Updated, err = store.Upsert(products)
+			result = out
+			changed, listChanged = products, out.Created > 0
+		}
+	}
+
+	var invalid *invalidInputError
+	switch {
+	case err == nil:
+		storeChanged(changed, listChanged)
+		return jsonToolResult(id, result)
+	case errors.As(err, &invalid):
+		return errorResponse(id, -32602, "Invalid arguments", err.Error())
+	case errors.Is(err, errProductNotFound):
+		return errorResponse(id, 1004, "NOT_FOUND", err.Error())
+	case errors.Is(err, errProductExists):
+		return errorResponse(id, 1005, "CONFLICT", err.Error())
+	default:
+		return errorResponse(id, 1003, "STORE_ERROR", err.Error())
+	}
+}
+
+type invalidInputError struct{ msg string }
+
+func (e *invalidInputError) Error() string { return e.msg }
+
+func invalidInput(format string, args ...any) error {
+	return &invalidInputError{msg: fmt.Sprintf(format, args...)}
+}
+
+// toProduct applies the input to current, or to a new product when current is
+// nil, in which case name and price are required.
+func (in ProductInput) toProduct(current *Product) (Product, error) {
+	p := Product{UserID: strings.TrimSpace(in.UserID), ID: strings.TrimSpace(in.ID), Currency: "IDR"}
+	if current != nil {
+		p = *current
+	}
+	if p.ID == "" {
+		return Product{}, invalidInput("id is required")
+	}
+	if in.Name != nil {
+		p.Name = strings.TrimSpace(*in.Name)
+	}
+	if p.Name == "" {
+		return Product{}, invalidInput("name is required")
+	}
+	if in.Price != nil {
+		p.Price = *in.Price
+	} else if current == nil {
+		return Product{}, invalidInput("price is required")
+	}
+	if p.Price < 0 {
+		return Product{}, invalidInput("price must not be negative")
+	}
+	if in.Currency != nil && strings.TrimSpace(*in.Currency) != "" {
+		p.Currency = strings.ToUpper(strings.TrimSpace(*in.Currency))
+	}
+	if in.ImageURL != nil {
+		img := strings.TrimSpace(*in.ImageURL)
+		switch {
+		case img == "":
+			p.ImageURL = nil
+		case strings.HasPrefix(strings.ToLower(img), "http"):
+			p.ImageURL = &img
+		default:
+			return Product{}, invalidInput("image_url must be an http(s) URL")
+		}
+	}
+	return p, nil
+}
+
+func (in UpsertProductsInput) toProducts() ([]Product, error) {
+	if len(in.Products) == 0 {
+		return nil, invalidInput("products must not be empty")
+	}
+	if len(in.Products) > maxUpsertProducts {
+		return nil, invalidInput("products must not have more than %d items", maxUpsertProducts)
+	}
+	seen := map[string]bool{}
+	products := make([]Product, 0, len(in.Products))
+	for i, item := range in.Products {
+		p, err := item.toProduct(nil)
+		if err != nil {
+			return nil, invalidInput("products[%d]: %v", i, err)
+		}
+		key := p.UserID + "\x00" + p.ID
+		if seen[key] {
+			return nil, invalidInput("products[%d]: duplicate id %q", i, p.ID)
+		}
+		seen[key] = true
+		products = append(products, p)
+	}
+	return products, nil
 }
 
 func fetchProducts(in FetchProductsInput) (FetchProductsOutput, error) {
-	client := &http.Client{Timeout: 20 * time.Second}
-	resp, err := client.Get(in.SheetURL)
+	allProducts, issues, cache, err := loadSheet(in.SheetURL, in.UserID, in.Refresh, in.Columns)
 	if err != nil {
-		return FetchProductsOutput{}, fmt.Errorf("http get failed: %w", err)
+		return FetchProductsOutput{}, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
-		return FetchProductsOutput{}, fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	out := paginate(allProducts, in)
+	if in.Offset == 0 {
+		out.Skipped, out.Warnings = splitIssues(issues)
 	}
+	out.Cache = cache
+	return out, nil
+}
 
-	all, err := io.ReadAll(resp.Body)
+// loadSheet reads the products of the sheet, only those of userID when it is
+// set, and the issues of its rows. refresh downloads the sheet again
+// whatever the cache holds and columns maps headers over SHEET_COLUMNS. The
+// cache info is nil for local files.
+func loadSheet(sheetURL, userID string, refresh bool, columns map[string]string) ([]Product, []RowIssue, *CacheInfo, error) {
+	columns, err := sheetColumns(columns)
 	if err != nil {
-		return FetchProductsOutput{}, fmt.Errorf("read body: %w", err)
+		return nil, nil, nil, err
 	}
-
-	r := csv.NewReader(bytes.NewReader(all))
-	r.TrimLeadingSpace = true
-	rows, err := r.ReadAll()
+	rows, cache, err := readSource(sheetURL, refresh)
 	if err != nil {
-		return FetchProductsOutput{}, fmt.Errorf("parse csv: %w", err)
+		return nil, nil, nil, err
 	}
 	if len(rows) == 0 {
-		return FetchProductsOutput{}, fmt.Errorf("empty csv")
-	}
-
-	// header -> index
-	header := make([]string, 0, len(rows[0]))
-	for _, h := range rows[0] {
-		header = append(header, strings.ToLower(strings.TrimSpace(h)))
+		return nil, nil, nil, fmt.Errorf("empty sheet")
 	}
-	idx := func(name string) int {
-		for i, h := range header {
-			if h == name {
-				return i
-			}
-		}
-		return -1
+	products, issues, err := parseRows(rows, userID, columns)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	return products, issues, cache, nil
+}
 
-	uidI := idx("user_id")
-	idI := idx("id")
-	nameI := idx("name")
-	priceI := idx("price")
-	currI := idx("currency")
-	imgI := idx("image_url")
+// parseRows turns the rows of a sheet, the header first, into products.
+// Rows that cannot be read are left out and reported with the other issues.
+func parseRows(rows [][]string, userID string, columns map[string]string) ([]Product, []RowIssue, error) {
+	idx, err := resolveColumns(rows[0], columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	uidI := idx[fieldUserID]
+	idI := idx[fieldID]
+	nameI := idx[fieldName]
+	priceI := idx[fieldPrice]
+	currI := idx[fieldCurrency]
+	imgI := idx[fieldImageURL]
 
-	if idI < 0 || nameI < 0 || priceI < 0 || currI < 0 {
-		return FetchProductsOutput{}, fmt.Errorf("missing required headers: need id,name,price,currency")
+	header := rows[0]
+	column := func(i int) string {
+		if i >= 0 && i < len(header) {
+			return strings.TrimSpace(header[i])
+		}
+		return ""
 	}
 
-	seen := map[string]bool{}
+	// generated holds the ids made from names, per owner.
+	generated := map[string]map[string]bool{}
+	// seen holds the row of every id, per owner.
+	seen := map[string]int{}
 	allProducts := make([]Product, 0, len(rows)-1)
+	var issues []RowIssue
 
-	for _, row := range rows[1:] {
+	for i, row := range rows[1:] {
 		if len(row) == 0 || (len(row) == 1 && strings.TrimSpace(row[0]) == "") {
 			continue
 		}
@@ -253,24 +701,61 @@ func fetchProducts(in FetchProductsInput) (FetchProductsOutput, error) {
 			uid = get(uidI)
 		}
 
-		id := get(idI)
-		if id == "" || seen[id] {
+		if userID != "" && uid != userID {
 			continue
 		}
+
+		id := get(idI)
+		report := func(severity string, col int, reason string) {
+			issues = append(issues, RowIssue{
+				Row:      i + 2,
+				UserID:   uid,
+				ID:       id,
+				Column:   column(col),
+				Value:    get(col),
+				Reason:   reason,
+				Severity: severity,
+			})
+		}
 		name := get(nameI)
+		if idI < 0 {
+			// the id is made from the name, so the name is what is missing
+			if name == "" {
+				report(issueError, nameI, "missing name")
+				continue
+			}
+			if generated[uid] == nil {
+				generated[uid] = map[string]bool{}
+			}
+			id = nameID(name, generated[uid])
+		}
+		if id == "" {
+			report(issueError, idI, "missing id")
+			continue
+		}
+		// ids are unique per owner, merchants sharing a sheet may reuse them
+		key := uid + "\x00" + id
+		if first, ok := seen[key]; ok {
+			report(issueError, idI, fmt.Sprintf("duplicate id, first used on row %d", first))
+			continue
+		}
 		if name == "" {
+			report(issueError, nameI, "missing name")
 			continue
 		}
 
-		if in.UserID != "" && uid != in.UserID {
+		if get(priceI) == "" {
+			report(issueError, priceI, "missing price")
 			continue
 		}
-
-		priceStr := strings.ReplaceAll(get(priceI), ",", "")
-		price, err := strconv.ParseFloat(priceStr, 64)
+		price, err := parsePrice(get(priceI))
 		if err != nil {
+			report(issueError, priceI, "invalid price")
 			continue
 		}
+		if price == 0 {
+			report(issueWarning, priceI, "price is 0")
+		}
 
 		curr := get(currI)
 		if curr == "" {
@@ -279,8 +764,12 @@ func fetchProducts(in FetchProductsInput) (FetchProductsOutput, error) {
 
 		var imgPtr *string
 		img := get(imgI)
-		if img != "" && strings.HasPrefix(strings.ToLower(img), "http") {
-			imgPtr = &img
+		if img != "" {
+			if strings.HasPrefix(strings.ToLower(img), "http") {
+				imgPtr = &img
+			} else {
+				report(issueWarning, imgI, "image url must start with http, ignored")
+			}
 		}
 
 		allProducts = append(allProducts, Product{
@@ -291,9 +780,13 @@ func fetchProducts(in FetchProductsInput) (FetchProductsOutput, error) {
 			Currency: curr,
 			ImageURL: imgPtr,
 		})
-		seen[id] = true
+		seen[key] = i + 2
 	}
 
+	return allProducts, issues, nil
+}
+
+func paginate(allProducts []Product, in FetchProductsInput) FetchProductsOutput {
 	total := len(allProducts)
 	start := in.Offset
 	if start > total {
@@ -315,10 +808,97 @@ func fetchProducts(in FetchProductsInput) (FetchProductsOutput, error) {
 		Items:      items,
 		Total:      total,
 		NextOffset: next,
-	}, nil
+	}
+}
+
+// defaultSource is the sheet, or the store when no sheet URL is set.
+func defaultSource(sheetURL string) string {
+	if sheetURL == "" && store != nil {
+		return sourceStore
+	}
+	return sourceSheet
 }
 
 // ---------- framing helpers ----------
+// Stdio messages are newline-delimited JSON as the MCP spec has it, or carry
+// LSP-style Content-Length headers for the clients written before.
+const (
+	framingAuto          = "auto"
+	framingNDJSON        = "ndjson"
+	framingContentLength = "content-length"
+)
+
+type stdioConn struct {
+	r       *bufio.Reader
+	framing string
+	// mu serializes the writes of responses and notifications.
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// read returns the next message. In auto mode the first message picks the
+// framing for the whole connection: a JSON message starts with { or [, a
+// header does not.
+func (c *stdioConn) read() ([]byte, error) {
+	if c.framing == framingAuto {
+		first, err := peekNonSpace(c.r)
+		if err != nil {
+			return nil, err
+		}
+		c.framing = framingContentLength
+		if first == '{' || first == '[' {
+			c.framing = framingNDJSON
+		}
+	}
+	if c.framing == framingNDJSON {
+		return readLineMessage(c.r)
+	}
+	return readFramedMessage(c.r)
+}
+
+// write answers in the framing of the connection. Before the first message
+// is read, auto mode writes newline-delimited JSON.
+func (c *stdioConn) write(v any) {
+	b, _ := json.Marshal(v)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.framing == framingContentLength {
+		writeFramedJSON(c.w, b)
+		return
+	}
+	c.w.Write(append(b, '\n'))
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.ReadByte()
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// readLineMessage returns the next non-empty line. Encoded JSON never holds a
+// raw newline, so a line is a whole message.
+func readLineMessage(r *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
 func readFramedMessage(r *bufio.Reader) ([]byte, error) {
 	var contentLength int
 	for {
@@ -350,24 +930,33 @@ func readFramedMessage(r *bufio.Reader) ([]byte, error) {
 	return buf, nil
 }
 
-func writeFramedJSON(b []byte) {
+func writeFramedJSON(w io.Writer, b []byte) {
 	h := fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/json\r\n\r\n", len(b))
-	os.Stdout.Write([]byte(h))
-	os.Stdout.Write(b)
+	w.Write([]byte(h))
+	w.Write(b)
+}
+
+// jsonToolResult returns v as the text content of a tool result.
+func jsonToolResult(id any, v any) *JSONRPCResponse {
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errorResponse(id, -32603, "Internal error", err.Error())
+	}
+	return resultResponse(id, ToolsCallResult{
+		Content: []ToolContent{{Type: "text", Text: string(blob)}},
+	})
 }
 
-func writeResult(id any, result any) {
-	resp := JSONRPCResponse{
+func resultResponse(id any, result any) *JSONRPCResponse {
+	return &JSONRPCResponse{
 		Jsonrpc: "2.0",
 		ID:      id,
 		Result:  result,
 	}
-	b, _ := json.Marshal(resp)
-	writeFramedJSON(b)
 }
 
-func writeError(id any, code int, message string, data any) {
-	resp := JSONRPCResponse{
+func errorResponse(id any, code int, message string, data any) *JSONRPCResponse {
+	return &JSONRPCResponse{
 		Jsonrpc: "2.0",
 		ID:      id,
 		Error: &RPCError{
@@ -376,8 +965,6 @@ func writeError(id any, code int, message string, data any) {
 			Data:    data,
 		},
 	}
-	b, _ := json.Marshal(resp)
-	writeFramedJSON(b)
 }
 
 func maxLimit() int {
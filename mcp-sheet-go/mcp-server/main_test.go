@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseRowsReportsIssues(t *testing.T) {
+	rows := [][]string{
+		{"user_id", "id", "name", "price", "image_url"},
+		{"u1", "p1", "Kopi", "15.000", ""},
+		{"u1", "", "Teh", "5000", ""},
+		{"u1", "p1", "Kopi Lagi", "1000", ""},
+		{"u2", "p1", "Kopi", "12000", ""},
+		{"u1", "p2", "", "1000", ""},
+		{"u1", "p3", "Roti", "", ""},
+		{"u1", "p4", "Susu", "nan", ""},
+		{"u1", "p5", "Air", "0", ""},
+		{"u1", "p6", "Gula", "3rb", "foto.jpg"},
+		{""},
+	}
+
+	products, issues, err := parseRows(rows, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range products {
+		got = append(got, p.UserID+"/"+p.ID)
+	}
+	if want := []string{"u1/p1", "u2/p1", "u1/p5", "u1/p6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("products = %v, want %v", got, want)
+	}
+	if p := products[3]; p.Price != 3000 || p.Currency != "IDR" || p.ImageURL != nil {
+		t.Errorf("unexpected product %+v", p)
+	}
+
+	want := []RowIssue{
+		{Row: 3, UserID: "u1", Column: "id", Reason: "missing id", Severity: issueError},
+		{Row: 4, UserID: "u1", ID: "p1", Column: "id", Value: "p1", Reason: "duplicate id, first used on row 2", Severity: issueError},
+		{Row: 6, UserID: "u1", ID: "p2", Column: "name", Reason: "missing name", Severity: issueError},
+		{Row: 7, UserID: "u1", ID: "p3", Column: "price", Reason: "missing price", Severity: issueError},
+		{Row: 8, UserID: "u1", ID: "p4", Column: "price", Value: "nan", Reason: "invalid price", Severity: issueError},
+		{Row: 9, UserID: "u1", ID: "p5", Column: "price", Value: "0", Reason: "price is 0", Severity: issueWarning},
+		{Row: 10, UserID: "u1", ID: "p6", Column: "image_url", Value: "foto.jpg", Reason: "image url must start with http, ignored", Severity: issueWarning},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues =\n%+v\nwant\n%+v", issues, want)
+	}
+
+	products, issues, err = parseRows(rows, "u2", nil)
+	if err != nil || len(products) != 1 || len(issues) != 0 {
+		t.Errorf("rows of u2 = %v, %v, %v, want one product without issues", products, issues, err)
+	}
+}
+
+func TestParseRowsWithoutIDColumn(t *testing.T) {
+	rows := [][]string{
+		{"Nama Produk", "Harga"},
+		{"Kopi Susu", "15.000"},
+		{"", "5000"},
+		{"Kopi Susu", "Rp 18.000"},
+	}
+	products, issues, err := parseRows(rows, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RowIssue{{Row: 3, Column: "Nama Produk", Reason: "missing name", Severity: issueError}}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %+v, want %+v", issues, want)
+	}
+	if len(products) != 2 || products[0].ID != "kopi-susu" || products[1].ID != "kopi-susu-2" || products[1].Price != 18000 {
+		t.Errorf("unexpected products %+v", products)
+	}
+}
+
+func TestStdioFramingDetection(t *testing.T) {
+	first := `{"jsonrpc":"2.0","id":1,"method":"initialize"}`
+	second := `[{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`
+	framed := func(header, msg string) string {
+		return header + ": " + strconv.Itoa(len(msg)) + "\r\nContent-Type: application/json\r\n\r\n" + msg
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		want        []string
+		wantFraming string
+		wantPrefix  string // of the written response
+	}{
+		{"ndjson", "\n" + first + "\n\n" + second + "\n", []string{first, second}, framingNDJSON, "{"},
+		{"ndjson batch first", second + "\n" + first + "\n", []string{second, first}, framingNDJSON, "{"},
+		{"content-length", framed("Content-Length", first) + framed("Content-Length", second), []string{first, second}, framingContentLength, "Content-Length: "},
+		{"content-length lower case", framed("content-length", first) + framed("content-length", second), []string{first, second}, framingContentLength, "Content-Length: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			conn := &stdioConn{r: bufio.NewReader(strings.NewReader(tt.input)), w: &out, framing: framingAuto}
+
+			var got []string
+			for {
+				msg, err := conn.read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("read: %v", err)
+				}
+				got = append(got, string(msg))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			if conn.framing != tt.wantFraming {
+				t.Errorf("framing = %s, want %s", conn.framing, tt.wantFraming)
+			}
+
+			conn.write(map[string]any{"jsonrpc": "2.0", "id": 1})
+			if !strings.HasPrefix(out.String(), tt.wantPrefix) {
+				t.Errorf("response %q does not start with %q", out.String(), tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ---------- Prompts ----------
+
+// maxPromptProducts caps the products put in a prompt.
+const maxPromptProducts = 30
+
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Arguments   []PromptArgument `json:"arguments"`
+}
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+type PromptsListResult struct {
+	Prompts []Prompt `json:"prompts"`
+}
+type PromptMessage struct {
+	Role    string      `json:"role"`
+	Content ToolContent `json:"content"`
+}
+type PromptsGetResult struct {
+	Description string          `json:"description"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+var catalogPromptArguments = []PromptArgument{
+	{Name: "user_id", Description: "Owner user_id (phone) of the catalog."},
+	{Name: "source", Description: "sheet or store. Defaults to the sheet, or the store when no sheet is set."},
+	{Name: "language", Description: "Language of the text, default Indonesian."},
+}
+
+var prompts = []Prompt{
+	{
+		Name:        "promo_caption",
+		Description: "Write a promo caption for products of a catalog.",
+		Arguments: append([]PromptArgument{
+			{Name: "product_ids", Description: "Comma separated product ids. Defaults to the whole catalog."},
+			{Name: "tone", Description: "Tone of the caption, e.g. playful or formal. Default friendly."},
+		}, catalogPromptArguments...),
+	},
+	{
+		Name:        "price_list",
+		Description: "Write a price list of a catalog to send in a chat.",
+		Arguments:   catalogPromptArguments,
+	},
+}
+
+func handlePromptsList(id any) *JSONRPCResponse {
+	return resultResponse(id, PromptsListResult{Prompts: prompts})
+}
+
+func handlePromptsGet(id any, params json.RawMessage) *JSONRPCResponse {
+	var p struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return errorResponse(id, -32602, "Invalid params", err.Error())
+	}
+	arg := func(name, def string) string {
+		if v := strings.TrimSpace(p.Arguments[name]); v != "" {
+			return v
+		}
+		return def
+	}
+	if p.Name != "promo_caption" && p.Name != "price_list" {
+		return errorResponse(id, -32602, "Invalid params", fmt.Sprintf("unknown prompt %q", p.Name))
+	}
+
+	source := arg("source", defaultSource(os.Getenv("SHEET_URL")))
+	products, err := loadCatalog(source, arg("user_id", ""))
+	if err != nil {
+		return errorResponse(id, 1002, "FETCH_ERROR", err.Error())
+	}
+	if ids := arg("product_ids", ""); ids != "" && p.Name == "promo_caption" {
+		products, err = pickProducts(products, strings.Split(ids, ","))
+		if err != nil {
+			return errorResponse(id, -32602, "Invalid params", err.Error())
+		}
+	}
+	if len(products) == 0 {
+		return errorResponse(id, -32602, "Invalid params", "the catalog has no products")
+	}
+	if len(products) > maxPromptProducts {
+		products = products[:maxPromptProducts]
+	}
+
+	language := arg("language", "Indonesian")
+	var b strings.Builder
+	var description string
+	switch p.Name {
+	case "promo_caption":
+		description = "Promo caption for the products"
+		fmt.Fprintf(&b, "Write a short promo caption in %s with a %s tone for these products:\n", language, arg("tone", "friendly"))
+		writeProductLines(&b, products)
+		b.WriteString("\nMention the prices, keep it under 80 words and end with an invitation to order by chat.")
+	case "price_list":
+		description = "Price list of the catalog"
+		fmt.Fprintf(&b, "Write a price list in %s to send in a WhatsApp chat for these products:\n", language)
+		writeProductLines(&b, products)
+		b.WriteString("\nKeep every product on its own line with its price, and add a short greeting on top.")
+	}
+	return resultResponse(id, PromptsGetResult{
+		Description: description,
+		Messages: []PromptMessage{
+			{Role: "user", Content: ToolContent{Type: "text", Text: b.String()}},
+		},
+	})
+}
+
+// pickProducts returns the products with the given ids, in the order of ids.
+func pickProducts(products []Product, ids []string) ([]Product, error) {
+	var picked []Product
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		found := false
+		for _, p := range products {
+			if p.ID == id {
+				picked = append(picked, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("product %q not found", id)
+		}
+	}
+	return picked, nil
+}
+
+func writeProductLines(b *strings.Builder, products []Product) {
+	for _, p := range products {
+		fmt.Fprintf(b, "- %s: %s %s\n", p.Name, p.Currency, formatPrice(p.Price))
+	}
+}
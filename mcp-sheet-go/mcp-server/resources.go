@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------- Resources ----------
+// Every merchant catalog and every product of the sheet (SHEET_URL) and of
+// the store is a resource:
+//
+//	catalog://<source>/<user_id>
+//	catalog://<source>/<user_id>/products/<id>
+//
+// Products without user_id belong to the catalog "_".
+
+const (
+	catalogScheme     = "catalog://"
+	noUserSegment     = "_"
+	resourcesPageSize = 100
+	resourceMimeType  = "application/json"
+	// resourceNotFound is the error code of the MCP spec for unknown URIs.
+	resourceNotFound = -32002
+)
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+}
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType"`
+}
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+type ResourcesListResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+type ResourceTemplatesListResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+type ResourcesReadResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// CatalogContents is the text of a catalog resource.
+type CatalogContents struct {
+	Source   string    `json:"source"`
+	UserID   string    `json:"user_id"`
+	Products []Product `json:"products"`
+}
+
+// resourceRef is a parsed resource URI. ProductID is empty for a catalog.
+type resourceRef struct {
+	Source    string
+	UserID    string
+	ProductID string
+}
+
+func catalogURI(source, userID string) string {
+	seg := noUserSegment
+	if userID != "" {
+		seg = url.PathEscape(userID)
+	}
+	return catalogScheme + source + "/" + seg
+}
+
+func productURI(source, userID, id string) string {
+	return catalogURI(source, userID) + "/products/" + url.PathEscape(id)
+}
+
+func parseResourceURI(uri string) (resourceRef, error) {
+	rest, ok := strings.CutPrefix(uri, catalogScheme)
+	if !ok {
+		return resourceRef{}, fmt.Errorf("unknown resource %q", uri)
+	}
+	parts := strings.Split(rest, "/")
+	if (len(parts) != 2 && len(parts) != 4) || (len(parts) == 4 && parts[2] != "products") {
+		return resourceRef{}, fmt.Errorf("unknown resource %q", uri)
+	}
+	var ref resourceRef
+	ref.Source = parts[0]
+	if ref.Source != sourceSheet && ref.Source != sourceStore {
+		return resourceRef{}, fmt.Errorf("unknown source %q in %q", ref.Source, uri)
+	}
+	if parts[1] != noUserSegment {
+		userID, err := url.PathUnescape(parts[1])
+		if err != nil {
+			return resourceRef{}, fmt.Errorf("invalid user_id in %q", uri)
+		}
+		ref.UserID = userID
+	}
+	if len(parts) == 4 {
+		id, err := url.PathUnescape(parts[3])
+		if err != nil || id == "" {
+			return resourceRef{}, fmt.Errorf("invalid product id in %q", uri)
+		}
+		ref.ProductID = id
+	}
+	return ref, nil
+}
+
+// availableSources are the sources configured in the environment.
+func availableSources() []string {
+	var sources []string
+	if os.Getenv("SHEET_URL") != "" {
+		sources = append(sources, sourceSheet)
+	}
+	if store != nil {
+		sources = append(sources, sourceStore)
+	}
+	return sources
+}
+
+// loadCatalog returns the products of source, only those of userID when it is
+// set.
+func loadCatalog(source, userID string) ([]Product, error) {
+	switch source {
+	case sourceSheet:
+		sheetURL := os.Getenv("SHEET_URL")
+		if sheetURL == "" {
+			return nil, fmt.Errorf("sheet not configured (SHEET_URL env)")
+		}
+		products, _, _, err := loadSheet(sheetURL, userID, false, nil)
+		return products, err
+	case sourceStore:
+		if store == nil {
+			return nil, fmt.Errorf("store not configured (STORE_TYPE and STORE_PATH env)")
+		}
+		return store.List(userID)
+	default:
+		return nil, fmt.Errorf("source must be sheet or store, got %q", source)
+	}
+}
+
+// catalogResources lists the catalogs of source, each followed by its
+// products.
+func catalogResources(source string) ([]Resource, error) {
+	products, err := loadCatalog(source, "")
+	if err != nil {
+		return nil, err
+	}
+	byUser := map[string][]Product{}
+	var users []string
+	for _, p := range products {
+		if _, ok := byUser[p.UserID]; !ok {
+			users = append(users, p.UserID)
+		}
+		byUser[p.UserID] = append(byUser[p.UserID], p)
+	}
+	sort.Strings(users)
+
+	var resources []Resource
+	for _, userID := range users {
+		owner := userID
+		if owner == "" {
+			owner = "without user_id"
+		}
+		resources = append(resources, Resource{
+			URI:         catalogURI(source, userID),
+			Name:        fmt.Sprintf("Catalog %s (%s)", owner, source),
+			Description: fmt.Sprintf("%d products", len(byUser[userID])),
+			MimeType:    resourceMimeType,
+		})
+		for _, p := range byUser[userID] {
+			resources = append(resources, Resource{
+				URI:         productURI(source, userID, p.ID),
+				Name:        p.Name,
+				Description: fmt.Sprintf("%s %s", p.Currency, formatPrice(p.Price)),
+				MimeType:    resourceMimeType,
+			})
+		}
+	}
+	return resources, nil
+}
+
+func handleResourcesList(id any, params json.RawMessage) *JSONRPCResponse {
+	var p struct {
+		Cursor string `json:"cursor"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return errorResponse(id, -32602, "Invalid params", err.Error())
+		}
+	}
+	offset := 0
+	if p.Cursor != "" {
+		n, err := strconv.Atoi(p.Cursor)
+		if err != nil || n < 0 {
+			return errorResponse(id, -32602, "Invalid params", "invalid cursor")
+		}
+		offset = n
+	}
+
+	var all []Resource
+	for _, source := range availableSources() {
+		resources, err := catalogResources(source)
+		if err != nil {
+			return errorResponse(id, 1002, "FETCH_ERROR", err.Error())
+		}
+		all = append(all, resources...)
+	}
+
+	res := ResourcesListResult{Resources: []Resource{}}
+	if offset < len(all) {
+		end := min(offset+resourcesPageSize, len(all))
+		res.Resources = all[offset:end]
+		if end < len(all) {
+			res.NextCursor = strconv.Itoa(end)
+		}
+	}
+	return resultResponse(id, res)
+}
+
+func handleResourceTemplatesList(id any) *JSONRPCResponse {
+	return resultResponse(id, ResourceTemplatesListResult{ResourceTemplates: []ResourceTemplate{
+		{
+			URITemplate: catalogScheme + "{source}/{user_id}",
+			Name:        "Merchant catalog",
+			Description: "Products of a merchant. source is sheet or store, user_id is " + noUserSegment + " for products without owner.",
+			MimeType:    resourceMimeType,
+		},
+		{
+			URITemplate: catalogScheme + "{source}/{user_id}/products/{id}",
+			Name:        "Product",
+			Description: "One product of a merchant catalog.",
+			MimeType:    resourceMimeType,
+		},
+	}})
+}
+
+func handleResourcesRead(id any, params json.RawMessage) *JSONRPCResponse {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return errorResponse(id, -32602, "Invalid params", err.Error())
+	}
+	ref, err := parseResourceURI(p.URI)
+	if err != nil {
+		return errorResponse(id, resourceNotFound, "Resource not found", err.Error())
+	}
+	products, err := loadCatalog(ref.Source, ref.UserID)
+	if err != nil {
+		return errorResponse(id, 1002, "FETCH_ERROR", err.Error())
+	}
+	// loadCatalog does not filter on an empty user_id.
+	owned := products[:0]
+	for _, prod := range products {
+		if prod.UserID == ref.UserID {
+			owned = append(owned, prod)
+		}
+	}
+
+	var v any
+	if ref.ProductID == "" {
+		if len(owned) == 0 {
+			return errorResponse(id, resourceNotFound, "Resource not found", p.URI)
+		}
+		v = CatalogContents{Source: ref.Source, UserID: ref.UserID, Products: owned}
+	} else {
+		for i := range owned {
+			if owned[i].ID == ref.ProductID {
+				v = owned[i]
+			}
+		}
+		if v == nil {
+			return errorResponse(id, resourceNotFound, "Resource not found", p.URI)
+		}
+	}
+	blob, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errorResponse(id, -32603, "Internal error", err.Error())
+	}
+	return resultResponse(id, ResourcesReadResult{Contents: []ResourceContents{
+		{URI: p.URI, MimeType: resourceMimeType, Text: string(blob)},
+	}})
+}
+
+func handleResourcesSubscribe(sub *subscriber, id any, params json.RawMessage, subscribe bool) *JSONRPCResponse {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return errorResponse(id, -32602, "Invalid params", err.Error())
+	}
+	if _, err := parseResourceURI(p.URI); err != nil {
+		return errorResponse(id, resourceNotFound, "Resource not found", err.Error())
+	}
+	sub.mu.Lock()
+	if subscribe {
+		sub.uris[p.URI] = true
+	} else {
+		delete(sub.uris, p.URI)
+	}
+	sub.mu.Unlock()
+	return resultResponse(id, struct{}{})
+}
+
+// ---------- Subscriptions ----------
+// subscriber is a connected client, a stdio connection or an http session,
+// and the resources it subscribed to.
+type subscriber struct {
+	send func(JSONRPCNotification)
+	mu   sync.Mutex
+	uris map[string]bool
+}
+
+var subscribers = struct {
+	mu  sync.Mutex
+	set map[*subscriber]bool
+}{set: map[*subscriber]bool{}}
+
+func newSubscriber(send func(JSONRPCNotification)) *subscriber {
+	sub := &subscriber{send: send, uris: map[string]bool{}}
+	subscribers.mu.Lock()
+	subscribers.set[sub] = true
+	subscribers.mu.Unlock()
+	return sub
+}
+
+func (s *subscriber) remove() {
+	subscribers.mu.Lock()
+	delete(subscribers.set, s)
+	subscribers.mu.Unlock()
+}
+
+func (s *subscriber) subscribed(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uris[uri]
+}
+
+func allSubscribers() []*subscriber {
+	subscribers.mu.Lock()
+	defer subscribers.mu.Unlock()
+	subs := make([]*subscriber, 0, len(subscribers.set))
+	for sub := range subscribers.set {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// notifyResourcesChanged sends resources/updated for the given URIs to their
+// subscribers, and resources/list_changed to everyone when resources were
+// added or removed.
+func notifyResourcesChanged(uris []string, listChanged bool) {
+	for _, sub := range allSubscribers() {
+		if listChanged {
+			sub.send(JSONRPCNotification{Jsonrpc: "2.0", Method: "notifications/resources/list_changed"})
+		}
+		for _, uri := range uris {
+			if sub.subscribed(uri) {
+				sub.send(JSONRPCNotification{
+					Jsonrpc: "2.0",
+					Method:  "notifications/resources/updated",
+					Params:  map[string]any{"uri": uri},
+				})
+			}
+		}
+	}
+}
+
+// storeChanged notifies the changes of a store write.
+func storeChanged(products []Product, listChanged bool) {
+	var uris []string
+	seen := map[string]bool{}
+	for _, p := range products {
+		catalog := catalogURI(sourceStore, p.UserID)
+		if !seen[catalog] {
+			seen[catalog] = true
+			uris = append(uris, catalog)
+		}
+		uris = append(uris, productURI(sourceStore, p.UserID, p.ID))
+	}
+	notifyResourcesChanged(uris, listChanged)
+}
+
+// sheetPollInterval is how often watchSheet reads the sheet, SHEET_POLL_INTERVAL
+// overrides it.
+func sheetPollInterval() time.Duration {
+	if v := os.Getenv("SHEET_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 5 * time.Minute
+}
+
+// watchSheet polls the sheet while someone is subscribed to one of its
+// resources and notifies what changed since the previous poll. Changes made
+// before the first poll after subscribing are not noticed.
+func watchSheet(ctx context.Context) {
+	ticker := time.NewTicker(sheetPollInterval())
+	defer ticker.Stop()
+	var previous map[string]string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sheetURL := os.Getenv("SHEET_URL")
+		if sheetURL == "" || !sheetSubscribed() {
+			previous = nil
+			continue
+		}
+		products, _, _, err := loadSheet(sheetURL, "", false, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "watch sheet:", err)
+			continue
+		}
+		current := sheetFingerprints(products)
+		if previous != nil {
+			var uris []string
+			listChanged := len(current) != len(previous)
+			for uri, fp := range current {
+				old, ok := previous[uri]
+				if !ok {
+					listChanged = true
+				}
+				if fp != old {
+					uris = append(uris, uri)
+				}
+			}
+			for uri := range previous {
+				if _, ok := current[uri]; !ok {
+					uris = append(uris, uri)
+				}
+			}
+			sort.Strings(uris)
+			if len(uris) > 0 {
+				notifyResourcesChanged(uris, listChanged)
+			}
+		}
+		previous = current
+	}
+}
+
+func sheetSubscribed() bool {
+	prefix := catalogScheme + sourceSheet + "/"
+	for _, sub := range allSubscribers() {
+		sub.mu.Lock()
+		for uri := range sub.uris {
+			if strings.HasPrefix(uri, prefix) {
+				sub.mu.Unlock()
+				return true
+			}
+		}
+		sub.mu.Unlock()
+	}
+	return false
+}
+
+// sheetFingerprints maps the URI of every sheet catalog and product to its
+// content.
+func sheetFingerprints(products []Product) map[string]string {
+	fps := map[string]string{}
+	catalogs := map[string][]Product{}
+	for _, p := range products {
+		blob, _ := json.Marshal(p)
+		fps[productURI(sourceSheet, p.UserID, p.ID)] = string(blob)
+		uri := catalogURI(sourceSheet, p.UserID)
+		catalogs[uri] = append(catalogs[uri], p)
+	}
+	for uri, ps := range catalogs {
+		blob, _ := json.Marshal(ps)
+		fps[uri] = string(blob)
+	}
+	return fps
+}
+
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
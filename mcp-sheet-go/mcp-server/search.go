@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ---------- search_products ----------
+
+// minSearchScore is the score a product needs to match the query.
+const minSearchScore = 0.5
+
+type SearchProductsInput struct {
+	SheetURL string   `json:"sheet_url,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	UserID   string   `json:"user_id,omitempty"`
+	Query    string   `json:"query,omitempty"`
+	MinPrice *float64 `json:"min_price,omitempty"`
+	MaxPrice *float64 `json:"max_price,omitempty"`
+	Currency string   `json:"currency,omitempty"`
+	HasImage *bool    `json:"has_image,omitempty"`
+	Sort     string   `json:"sort,omitempty"`  // relevance, price or name
+	Order    string   `json:"order,omitempty"` // asc or desc
+	Limit    int      `json:"limit,omitempty"`
+	Offset   int      `json:"offset,omitempty"`
+	Refresh  bool     `json:"refresh,omitempty"`
+	// Columns maps fields to the headers of the sheet.
+	Columns map[string]string `json:"columns,omitempty"`
+}
+
+// SearchResult is a matching product and how well its name matches the
+// query, from 0 to 1. Without a query every product scores 1.
+type SearchResult struct {
+	Product
+	Score float64 `json:"score"`
+}
+
+type SearchProductsOutput struct {
+	Items      []SearchResult `json:"items"`
+	Total      int            `json:"total"`
+	NextOffset *int           `json:"next_offset,omitempty"`
+	Cache      *CacheInfo     `json:"cache,omitempty"`
+}
+
+var searchProductsTool = Tool{
+	Name:        "search_products",
+	Description: "Search the products of the sheet or the store by name (typo tolerant), price range, currency and image. Results have a relevance score and are sorted by relevance unless sort says otherwise.",
+	InputSchema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"sheet_url": map[string]any{"type": "string", "description": sheetURLDescription},
+			"source":    map[string]any{"type": "string", "enum": []string{sourceSheet, sourceStore}, "description": "Where to read from. Defaults to the sheet, or the store when no sheet URL is set."},
+			"user_id":   map[string]any{"type": "string", "description": "Optional filter by owner user_id (phone)."},
+			"query":     map[string]any{"type": "string", "description": "Words to look for in the product name."},
+			"min_price": map[string]any{"type": "number", "minimum": 0},
+			"max_price": map[string]any{"type": "number", "minimum": 0, "description": "e.g. 20000 for \"under 20rb\"."},
+			"currency":  map[string]any{"type": "string"},
+			"has_image": map[string]any{"type": "boolean"},
+			"sort":      map[string]any{"type": "string", "enum": []string{"relevance", "price", "name"}},
+			"order":     map[string]any{"type": "string", "enum": []string{"asc", "desc"}, "description": "Default desc for relevance, asc otherwise."},
+			"limit":     map[string]any{"type": "number", "description": "Max items (default 50)."},
+			"offset":    map[string]any{"type": "number", "description": "Offset (default 0)."},
+			"refresh":   map[string]any{"type": "boolean", "description": "Download the sheet again instead of using the cache."},
+			"columns":   columnsSchema(),
+		},
+		"additionalProperties": false,
+	},
+}
+
+func handleSearchProducts(id any, args json.RawMessage) *JSONRPCResponse {
+	var in SearchProductsInput
+	if err := json.Unmarshal(args, &in); err != nil {
+		return errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+	if err := in.normalize(); err != nil {
+		return errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+
+	var (
+		products []Product
+		cache    *CacheInfo
+		err      error
+	)
+	switch in.Source {
+	case sourceSheet:
+		if in.SheetURL == "" {
+			return errorResponse(id, 1001, "CONFIG_ERROR", "sheet_url missing (arg or SHEET_URL env)")
+		}
+		products, _, cache, err = loadSheet(in.SheetURL, in.UserID, in.Refresh, in.Columns)
+		if err != nil {
+			return errorResponse(id, 1002, "FETCH_ERROR", err.Error())
+		}
+	case sourceStore:
+		if store == nil {
+			return errorResponse(id, 1001, "CONFIG_ERROR", "store missing (STORE_TYPE and STORE_PATH env)")
+		}
+		products, err = store.List(in.UserID)
+		if err != nil {
+			return errorResponse(id, 1003, "STORE_ERROR", err.Error())
+		}
+	}
+
+	results := searchProducts(products, in)
+	out := SearchProductsOutput{Items: []SearchResult{}, Total: len(results), Cache: cache}
+	if in.Offset < len(results) {
+		end := min(in.Offset+in.Limit, len(results))
+		out.Items = results[in.Offset:end]
+		if end < len(results) {
+			out.NextOffset = &end
+		}
+	}
+	return jsonToolResult(id, out)
+}
+
+// normalize applies the defaults and checks the arguments.
+func (in *SearchProductsInput) normalize() error {
+	if in.Limit <= 0 {
+		in.Limit = 50
+	}
+	if in.Limit > maxLimit() {
+		in.Limit = maxLimit()
+	}
+	if in.Offset < 0 {
+		in.Offset = 0
+	}
+	if in.SheetURL == "" {
+		in.SheetURL = os.Getenv("SHEET_URL")
+	}
+	if in.Source == "" {
+		in.Source = defaultSource(in.SheetURL)
+	}
+	if in.Source != sourceSheet && in.Source != sourceStore {
+		return fmt.Errorf("source must be sheet or store, got %q", in.Source)
+	}
+	if err := checkColumns(in.Columns); err != nil {
+		return err
+	}
+	if (in.MinPrice != nil && *in.MinPrice < 0) || (in.MaxPrice != nil && *in.MaxPrice < 0) {
+		return fmt.Errorf("prices must not be negative")
+	}
+	if in.MinPrice != nil && in.MaxPrice != nil && *in.MinPrice > *in.MaxPrice {
+		return fmt.Errorf("min_price must not be above max_price")
+	}
+	if in.Sort == "" {
+		in.Sort = "relevance"
+	}
+	switch in.Sort {
+	case "relevance", "price", "name":
+	default:
+		return fmt.Errorf("sort must be relevance, price or name, got %q", in.Sort)
+	}
+	if in.Order == "" {
+		in.Order = "asc"
+		if in.Sort == "relevance" {
+			in.Order = "desc"
+		}
+	}
+	if in.Order != "asc" && in.Order != "desc" {
+		return fmt.Errorf("order must be asc or desc, got %q", in.Order)
+	}
+	return nil
+}
+
+// searchProducts filters, scores and sorts the products.
+func searchProducts(products []Product, in SearchProductsInput) []SearchResult {
+	query := searchTokens(in.Query)
+	results := []SearchResult{}
+	for _, p := range products {
+		if in.MinPrice != nil && p.Price < *in.MinPrice {
+			continue
+		}
+		if in.MaxPrice != nil && p.Price > *in.MaxPrice {
+			continue
+		}
+		if in.Currency != "" && !strings.EqualFold(p.Currency, in.Currency) {
+			continue
+		}
+		if in.HasImage != nil && (p.ImageURL != nil) != *in.HasImage {
+			continue
+		}
+		score := 1.0
+		if len(query) > 0 {
+			score = matchScore(query, p.Name)
+			if score < minSearchScore {
+				continue
+			}
+		}
+		results = append(results, SearchResult{Product: p, Score: score})
+	}
+
+	less := func(a, b SearchResult) bool {
+		switch in.Sort {
+		case "price":
+			return a.Price < b.Price
+		case "name":
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		default:
+			return a.Score < b.Score
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if in.Order == "desc" {
+			return less(results[j], results[i])
+		}
+		return less(results[i], results[j])
+	})
+	return results
+}
+
+// matchScore rates how well name matches the query tokens: every token scores
+// its best match among the words of the name and the scores are averaged.
+func matchScore(query []string, name string) float64 {
+	words := searchTokens(name)
+	if len(words) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, q := range query {
+		best := 0.0
+		for _, w := range words {
+			best = max(best, tokenScore(q, w))
+		}
+		total += best
+	}
+	score := total / float64(len(query))
+	// The query as written in the name ranks above the same words scattered.
+	if len(query) > 1 && strings.Contains(strings.Join(words, " "), strings.Join(query, " ")) {
+		score = min(1, score+0.1)
+	}
+	return roundScore(score)
+}
+
+func tokenScore(q, w string) float64 {
+	switch {
+	case q == w:
+		return 1
+	case strings.HasPrefix(w, q):
+		return 0.9
+	case strings.Contains(w, q):
+		return 0.75
+	}
+	// Typos: one edit per four letters is still a match.
+	d := levenshtein(q, w)
+	longest := max(len([]rune(q)), len([]rune(w)))
+	if d <= 0 || d > max(1, longest/4) {
+		return 0
+	}
+	return 0.7 * (1 - float64(d)/float64(longest))
+}
+
+// searchTokens splits s into lower case words of letters and digits.
+func searchTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func roundScore(score float64) float64 {
+	return float64(int(score*1000+0.5)) / 1000
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- Sources ----------
+// A sheet URL may point to:
+//
+//   - a CSV, XLSX or JSON file over http(s)
+//   - a Google Sheet, the tab picked with gid as Google does (?gid= or #gid=)
+//   - a local file:// CSV, XLSX or JSON inside SOURCE_FILE_ROOT
+//
+// The tab of a workbook is picked with #sheet=<name>, the first one by
+// default. Every format ends as rows of cells, the header first, that
+// parseRows reads the same way.
+
+// Formats of a source.
+const (
+	formatCSV  = "csv"
+	formatXLSX = "xlsx"
+	formatJSON = "json"
+)
+
+const sheetURLDescription = "CSV, XLSX or JSON URL, a Google Sheet link (tab by gid) or a file:// path. Pick an XLSX tab with #sheet=<name>. If omitted, uses SHEET_URL env."
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// readSource returns the rows of the sheet at rawURL.
+func readSource(rawURL string, refresh bool) ([][]string, *CacheInfo, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sheet url: %w", err)
+	}
+	fragment, _ := url.ParseQuery(u.Fragment)
+	tab := fragment.Get("sheet")
+
+	var (
+		body        []byte
+		contentType string
+		cache       *CacheInfo
+	)
+	switch u.Scheme {
+	case "http", "https":
+		if !allowedSheetURL(rawURL, u) {
+			return nil, nil, fmt.Errorf("sheet url host %q is not allowed (SHEET_URL_HOSTS env)", u.Hostname())
+		}
+		u = googleSheetExportURL(u)
+		u.Fragment = ""
+		body, contentType, cache, err = sheets.get(u.String(), refresh)
+	case "file":
+		body, err = readLocalFile(u)
+	default:
+		return nil, nil, fmt.Errorf("unsupported sheet url scheme %q (http, https or file)", u.Scheme)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]string
+	switch format := detectFormat(u, contentType, body); format {
+	case formatXLSX:
+		rows, err = xlsxRows(body, tab)
+	case formatJSON:
+		rows, err = jsonRows(body)
+	default:
+		rows, err = csvRows(body)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, cache, nil
+}
+
+// googleSheetExportURL turns the link of a Google Sheet, as copied from the
+// browser, into the CSV export of its tab. Other URLs are returned as is.
+func googleSheetExportURL(u *url.URL) *url.URL {
+	if u.Host != "docs.google.com" || !strings.HasPrefix(u.Path, "/spreadsheets/d/") {
+		return u
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/spreadsheets/d/"), "/")
+	// Published (/d/e/...) and export links already serve a file.
+	if parts[0] == "e" || (len(parts) > 1 && (parts[1] == "export" || parts[1] == "gviz")) {
+		return u
+	}
+	gid := u.Query().Get("gid")
+	if gid == "" {
+		fragment, _ := url.ParseQuery(u.Fragment)
+		gid = fragment.Get("gid")
+	}
+	q := url.Values{"format": {"csv"}}
+	if gid != "" {
+		q.Set("gid", gid)
+	}
+	return &url.URL{
+		Scheme:   "https",
+		Host:     u.Host,
+		Path:     "/spreadsheets/d/" + parts[0] + "/export",
+		RawQuery: q.Encode(),
+	}
+}
+
+// readLocalFile reads a file:// URL. Only files inside SOURCE_FILE_ROOT can
+// be read, since the url may come from a remote client.
+func readLocalFile(u *url.URL) ([]byte, error) {
+	root := os.Getenv("SOURCE_FILE_ROOT")
+	if root == "" {
+		return nil, fmt.Errorf("file urls need SOURCE_FILE_ROOT env")
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("file url must be local, got host %q", u.Host)
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Clean(filepath.FromSlash(u.Path))
+	if rel, err := filepath.Rel(root, name); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("file %s is outside SOURCE_FILE_ROOT", name)
+	}
+	body, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return body, nil
+}
+
+// detectFormat picks the format from the URL, then the Content-Type, then the
+// content itself.
+func detectFormat(u *url.URL, contentType string, body []byte) string {
+	if f := u.Query().Get("format"); f == formatXLSX || f == formatCSV {
+		return f
+	}
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".xlsx":
+		return formatXLSX
+	case ".json":
+		return formatJSON
+	case ".csv":
+		return formatCSV
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case xlsxContentType:
+			return formatXLSX
+		case "application/json":
+			return formatJSON
+		case "text/csv":
+			return formatCSV
+		}
+	}
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case bytes.HasPrefix(body, []byte("PK\x03\x04")):
+		return formatXLSX
+	case len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{'):
+		return formatJSON
+	}
+	return formatCSV
+}
+
+func csvRows(body []byte) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.TrimLeadingSpace = true
+	// Rows may be shorter than the header, parseRows handles missing cells.
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv: %w", err)
+	}
+	return rows, nil
+}
+
+// xlsxRows reads the tab of the workbook, or its first tab when tab is empty.
+func xlsxRows(body []byte, tab string) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse xlsx: %w", err)
+	}
+	defer f.Close()
+
+	tabs := f.GetSheetList()
+	if len(tabs) == 0 {
+		return nil, fmt.Errorf("xlsx has no sheets")
+	}
+	if tab == "" {
+		tab = tabs[0]
+	} else if idx, _ := f.GetSheetIndex(tab); idx < 0 {
+		return nil, fmt.Errorf("xlsx has no sheet %q (sheets: %s)", tab, strings.Join(tabs, ", "))
+	}
+	// Raw values keep prices as numbers instead of their display format.
+	rows, err := f.GetRows(tab, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("read xlsx sheet %q: %w", tab, err)
+	}
+	return rows, nil
+}
+
+// jsonRows reads an array of objects, or an object holding one in products or
+// items, as rows with the keys as header.
+func jsonRows(body []byte) ([][]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse json: %w", err)
+	}
+	if obj, ok := doc.(map[string]any); ok {
+		for _, key := range []string{"products", "items"} {
+			if list, ok := obj[key]; ok {
+				doc = list
+				break
+			}
+		}
+	}
+	list, ok := doc.([]any)
+	if !ok {
+		return nil, fmt.Errorf("json must be an array of products")
+	}
+
+	keys := map[string]bool{}
+	for i, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("json item %d is not an object", i)
+		}
+		for key := range obj {
+			keys[key] = true
+		}
+	}
+	header := make([]string, 0, len(keys))
+	for key := range keys {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+
+	rows := [][]string{header}
+	for _, item := range list {
+		obj := item.(map[string]any)
+		row := make([]string, len(header))
+		for i, key := range header {
+			row[i] = jsonCell(obj[key])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func jsonCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ---------- Store ----------
+// Store keeps the products written through the write tools. Products are
+// identified by user_id and id, so merchants may reuse ids.
+type Store interface {
+	List(userID string) ([]Product, error)
+	Get(userID, id string) (*Product, error)
+	// Insert fails with errProductExists when the product already exists.
+	Insert(p Product) error
+	// Update fails with errProductNotFound when the product does not exist.
+	Update(p Product) error
+	// Delete fails with errProductNotFound when the product does not exist.
+	Delete(userID, id string) error
+	// Upsert writes all products at once.
+	Upsert(products []Product) (created int, updated int, err error)
+}
+
+var (
+	errProductExists   = errors.New("product already exists")
+	errProductNotFound = errors.New("product not found")
+)
+
+// openStore opens the store selected by STORE_TYPE (sqlite or csv) at
+// STORE_PATH. It returns nil when STORE_TYPE is not set.
+func openStore() (Store, error) {
+	storeType := strings.ToLower(strings.TrimSpace(os.Getenv("STORE_TYPE")))
+	path := os.Getenv("STORE_PATH")
+	if storeType == "" {
+		return nil, nil
+	}
+	if path == "" {
+		return nil, fmt.Errorf("STORE_PATH is required with STORE_TYPE=%s", storeType)
+	}
+	switch storeType {
+	case "sqlite":
+		return openSQLiteStore(path)
+	case "csv":
+		return &csvStore{path: path}, nil
+	default:
+		return nil, fmt.Errorf("STORE_TYPE must be sqlite or csv, got %q", storeType)
+	}
+}
+
+// ---------- SQLite ----------
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes the writes of concurrent http sessions
+	// instead of failing them with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS products (
+		user_id TEXT NOT NULL,
+		id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		price REAL NOT NULL,
+		currency TEXT NOT NULL,
+		image_url TEXT,
+		PRIMARY KEY (user_id, id)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create products table: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) List(userID string) ([]Product, error) {
+	query := `SELECT user_id, id, name, price, currency, image_url FROM products`
+	var args []any
+	if userID != "" {
+		query += ` WHERE user_id = ?`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY user_id, rowid`
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *p)
+	}
+	return products, rows.Err()
+}
+
+func (s *sqliteStore) Get(userID, id string) (*Product, error) {
+	row := s.db.QueryRow(`SELECT user_id, id, name, price, currency, image_url FROM products WHERE user_id = ? AND id = ?`, userID, id)
+	p, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errProductNotFound
+	}
+	return p, err
+}
+
+func (s *sqliteStore) Insert(p Product) error {
+	if _, err := s.Get(p.UserID, p.ID); err == nil {
+		return errProductExists
+	} else if !errors.Is(err, errProductNotFound) {
+		return err
+	}
+	_, err := s.db.Exec(`INSERT INTO products (user_id, id, name, price, currency, image_url) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.UserID, p.ID, p.Name, p.Price, p.Currency, p.ImageURL)
+	return err
+}
+
+func (s *sqliteStore) Update(p Product) error {
+	res, err := s.db.Exec(`UPDATE products SET name = ?, price = ?, currency = ?, image_url = ? WHERE user_id = ? AND id = ?`,
+		p.Name, p.Price, p.Currency, p.ImageURL, p.UserID, p.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (s *sqliteStore) Delete(userID, id string) error {
+	res, err := s.db.Exec(`DELETE FROM products WHERE user_id = ? AND id = ?`, userID, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (s *sqliteStore) Upsert(products []Product) (int, int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	created, updated := 0, 0
+	for _, p := range products {
+		res, err := tx.Exec(`UPDATE products SET name = ?, price = ?, currency = ?, image_url = ? WHERE user_id = ? AND id = ?`,
+			p.Name, p.Price, p.Currency, p.ImageURL, p.UserID, p.ID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			updated++
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO products (user_id, id, name, price, currency, image_url) VALUES (?, ?, ?, ?, ?, ?)`,
+			p.UserID, p.ID, p.Name, p.Price, p.Currency, p.ImageURL); err != nil {
+			return 0, 0, err
+		}
+		created++
+	}
+	return created, updated, tx.Commit()
+}
+
+func scanProduct(row interface{ Scan(...any) error }) (*Product, error) {
+	var p Product
+	var img sql.NullString
+	if err := row.Scan(&p.UserID, &p.ID, &p.Name, &p.Price, &p.Currency, &img); err != nil {
+		return nil, err
+	}
+	if img.Valid && img.String != "" {
+		p.ImageURL = &img.String
+	}
+	return &p, nil
+}
+
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errProductNotFound
+	}
+	return nil
+}
+
+// ---------- CSV ----------
+// csvStore keeps the products in a CSV file with the same headers as the
+// sheet. The whole file is rewritten on every write.
+type csvStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+var csvHeader = []string{"user_id", "id", "name", "price", "currency", "image_url"}
+
+func (s *csvStore) List(userID string) ([]Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	products := []Product{}
+	for _, p := range all {
+		if userID == "" || p.UserID == userID {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
+func (s *csvStore) Get(userID, id string) (*Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if i := indexProduct(all, userID, id); i >= 0 {
+		return &all[i], nil
+	}
+	return nil, errProductNotFound
+}
+
+func (s *csvStore) Insert(p Product) error {
+	return s.modify(func(all []Product) ([]Product, error) {
+		if indexProduct(all, p.UserID, p.ID) >= 0 {
+			return nil, errProductExists
+		}
+		return append(all, p), nil
+	})
+}
+
+func (s *csvStore) Update(p Product) error {
+	return s.modify(func(all []Product) ([]Product, error) {
+		i := indexProduct(all, p.UserID, p.ID)
+		if i < 0 {
+			return nil, errProductNotFound
+		}
+		all[i] = p
+		return all, nil
+	})
+}
+
+func (s *csvStore) Delete(userID, id string) error {
+	return s.modify(func(all []Product) ([]Product, error) {
+		i := indexProduct(all, userID, id)
+		if i < 0 {
+			return nil, errProductNotFound
+		}
+		return append(all[:i], all[i+1:]...), nil
+	})
+}
+
+func (s *csvStore) Upsert(products []Product) (int, int, error) {
+	created, updated := 0, 0
+	err := s.modify(func(all []Product) ([]Product, error) {
+		for _, p := range products {
+			if i := indexProduct(all, p.UserID, p.ID); i >= 0 {
+				all[i] = p
+				updated++
+				continue
+			}
+			all = append(all, p)
+			created++
+		}
+		return all, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
+// modify loads the products, applies fn and writes the result.
+func (s *csvStore) modify(fn func([]Product) ([]Product, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	all, err = fn(all)
+	if err != nil {
+		return err
+	}
+	return s.save(all)
+}
+
+func (s *csvStore) load() ([]Product, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(csvHeader)
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse store csv: %w", err)
+	}
+	products := make([]Product, 0, len(rows))
+	for i, row := range rows {
+		if i == 0 {
+			continue // header
+		}
+		price, err := strconv.ParseFloat(row[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("store csv line %d: invalid price %q", i+1, row[3])
+		}
+		p := Product{UserID: row[0], ID: row[1], Name: row[2], Price: price, Currency: row[4]}
+		if row[5] != "" {
+			img := row[5]
+			p.ImageURL = &img
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+// save writes the products to a temp file and renames it over the store, so
+// a failed write never leaves a truncated store behind.
+func (s *csvStore) save(products []Product) error {
+	sort.SliceStable(products, func(i, j int) bool { return products[i].UserID < products[j].UserID })
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".store-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := csv.NewWriter(tmp)
+	w.Write(csvHeader)
+	for _, p := range products {
+		img := ""
+		if p.ImageURL != nil {
+			img = *p.ImageURL
+		}
+		w.Write([]string{p.UserID, p.ID, p.Name, strconv.FormatFloat(p.Price, 'f', -1, 64), p.Currency, img})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func indexProduct(products []Product, userID, id string) int {
+	for i, p := range products {
+		if p.UserID == userID && p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
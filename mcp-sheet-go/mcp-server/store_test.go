@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"sqlite": func(t *testing.T) Store {
+			s, err := openSQLiteStore(filepath.Join(t.TempDir(), "store.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { s.db.Close() })
+			return s
+		},
+		"csv": func(t *testing.T) Store {
+			return &csvStore{path: filepath.Join(t.TempDir(), "store.csv")}
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			testStore(t, open(t))
+		})
+	}
+}
+
+func testStore(t *testing.T, s Store) {
+	img := "https://example.com/kopi.jpg"
+	kopi := Product{UserID: "u1", ID: "p1", Name: "Kopi", Price: 15000, Currency: "IDR", ImageURL: &img}
+
+	if err := s.Insert(kopi); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := s.Insert(kopi); !errors.Is(err, errProductExists) {
+		t.Errorf("Insert of an existing product = %v, want errProductExists", err)
+	}
+	// ids are unique per owner
+	if err := s.Insert(Product{UserID: "u2", ID: "p1", Name: "Kopi Tubruk", Price: 8000, Currency: "IDR"}); err != nil {
+		t.Errorf("Insert of the same id for another owner: %v", err)
+	}
+
+	got, err := s.Get("u1", "p1")
+	if err != nil || got.Name != "Kopi" || got.Price != 15000 || got.ImageURL == nil || *got.ImageURL != img {
+		t.Errorf("Get = %+v, %v, want %+v", got, err, kopi)
+	}
+	if _, err := s.Get("u1", "missing"); !errors.Is(err, errProductNotFound) {
+		t.Errorf("Get of a missing product = %v, want errProductNotFound", err)
+	}
+
+	kopi.Price = 17500
+	kopi.ImageURL = nil
+	if err := s.Update(kopi); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, err := s.Get("u1", "p1"); err != nil || got.Price != 17500 || got.ImageURL != nil {
+		t.Errorf("Get after Update = %+v, %v", got, err)
+	}
+	if err := s.Update(Product{UserID: "u1", ID: "missing", Name: "Teh", Currency: "IDR"}); !errors.Is(err, errProductNotFound) {
+		t.Errorf("Update of a missing product = %v, want errProductNotFound", err)
+	}
+
+	created, updated, err := s.Upsert([]Product{
+		{UserID: "u1", ID: "p1", Name: "Kopi Susu", Price: 18000, Currency: "IDR"},
+		{UserID: "u1", ID: "p2", Name: "Teh", Price: 5000, Currency: "IDR"},
+	})
+	if err != nil || created != 1 || updated != 1 {
+		t.Errorf("Upsert = %d created, %d updated, %v, want 1 and 1", created, updated, err)
+	}
+	products, err := s.List("u1")
+	if err != nil || len(products) != 2 || products[0].Name != "Kopi Susu" || products[1].ID != "p2" {
+		t.Errorf("List(u1) = %+v, %v", products, err)
+	}
+	if all, err := s.List(""); err != nil || len(all) != 3 {
+		t.Errorf("List of every owner = %+v, %v, want 3 products", all, err)
+	}
+
+	if err := s.Delete("u1", "p1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete("u1", "p1"); !errors.Is(err, errProductNotFound) {
+		t.Errorf("Delete of a deleted product = %v, want errProductNotFound", err)
+	}
+	if _, err := s.Get("u2", "p1"); err != nil {
+		t.Errorf("Delete removed the product of another owner: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ---------- validate_sheet ----------
+
+// maxReportedIssues caps the issues listed by validate_sheet, the counts
+// still cover every row.
+const maxReportedIssues = 200
+
+type ValidateSheetInput struct {
+	SheetURL string            `json:"sheet_url,omitempty"`
+	UserID   string            `json:"user_id,omitempty"`
+	Refresh  bool              `json:"refresh,omitempty"`
+	Columns  map[string]string `json:"columns,omitempty"`
+}
+
+// ValidateSheetOutput summarizes the problems of a sheet. Valid is true when
+// every row can be read, warnings aside.
+type ValidateSheetOutput struct {
+	Valid bool `json:"valid"`
+	// HeaderError is set when the header lacks required columns, no row is
+	// checked then.
+	HeaderError string `json:"header_error,omitempty"`
+	// Columns is the header used for every field found.
+	Columns map[string]string `json:"columns,omitempty"`
+	// IgnoredColumns are headers no field uses.
+	IgnoredColumns []string `json:"ignored_columns,omitempty"`
+	Rows           int      `json:"rows"`     // rows checked
+	Products       int      `json:"products"` // rows that become products
+	Errors         int      `json:"errors"`
+	Warnings       int      `json:"warnings"`
+	// Reasons counts the issues by reason.
+	Reasons   map[string]int `json:"reasons,omitempty"`
+	Issues    []RowIssue     `json:"issues"`
+	Truncated bool           `json:"truncated,omitempty"`
+	Cache     *CacheInfo     `json:"cache,omitempty"`
+}
+
+var validateSheetTool = Tool{
+	Name:        "validate_sheet",
+	Description: "Check a sheet without importing it: which columns were found, and every row with a missing name or id, an invalid price, a duplicate id or an ignored cell, with its row number, column and raw value.",
+	InputSchema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"sheet_url": map[string]any{"type": "string", "description": sheetURLDescription},
+			"user_id":   map[string]any{"type": "string", "description": "Optional filter by owner user_id (phone)."},
+			"refresh":   map[string]any{"type": "boolean", "description": "Download the sheet again instead of using the cache."},
+			"columns":   columnsSchema(),
+		},
+		"additionalProperties": false,
+	},
+}
+
+func handleValidateSheet(id any, args json.RawMessage) *JSONRPCResponse {
+	var in ValidateSheetInput
+	if err := json.Unmarshal(args, &in); err != nil {
+		return errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+	if err := checkColumns(in.Columns); err != nil {
+		return errorResponse(id, -32602, "Invalid arguments", err.Error())
+	}
+	if in.SheetURL == "" {
+		in.SheetURL = os.Getenv("SHEET_URL")
+	}
+	if in.SheetURL == "" {
+		return errorResponse(id, 1001, "CONFIG_ERROR", "sheet_url missing (arg or SHEET_URL env)")
+	}
+	columns, err := sheetColumns(in.Columns)
+	if err != nil {
+		return errorResponse(id, 1001, "CONFIG_ERROR", err.Error())
+	}
+	rows, cache, err := readSource(in.SheetURL, in.Refresh)
+	if err != nil {
+		return errorResponse(id, 1002, "FETCH_ERROR", err.Error())
+	}
+	return jsonToolResult(id, validateRows(rows, in.UserID, columns, cache))
+}
+
+// validateRows checks the rows of a sheet, the header first.
+func validateRows(rows [][]string, userID string, columns map[string]string, cache *CacheInfo) ValidateSheetOutput {
+	out := ValidateSheetOutput{Issues: []RowIssue{}, Cache: cache}
+	if len(rows) == 0 {
+		out.HeaderError = "empty sheet"
+		return out
+	}
+	header := rows[0]
+	idx, err := resolveColumns(header, columns)
+	if err != nil {
+		out.HeaderError = err.Error()
+		return out
+	}
+
+	used := map[int]bool{}
+	out.Columns = map[string]string{}
+	for _, field := range allFields {
+		if i := idx[field]; i >= 0 {
+			out.Columns[field] = strings.TrimSpace(header[i])
+			used[i] = true
+		}
+	}
+	for i, h := range header {
+		if !used[i] && strings.TrimSpace(h) != "" {
+			out.IgnoredColumns = append(out.IgnoredColumns, strings.TrimSpace(h))
+		}
+	}
+
+	products, issues, err := parseRows(rows, userID, columns)
+	if err != nil {
+		out.HeaderError = err.Error()
+		return out
+	}
+	out.Products = len(products)
+	out.Reasons = map[string]int{}
+	rowsWithError := map[int]bool{}
+	for _, issue := range issues {
+		if issue.Severity == issueError {
+			out.Errors++
+			rowsWithError[issue.Row] = true
+		} else {
+			out.Warnings++
+		}
+		out.Reasons[reasonKey(issue.Reason)]++
+	}
+	out.Rows = out.Products + len(rowsWithError)
+	out.Valid = out.Errors == 0
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Row < issues[j].Row })
+	if len(issues) > maxReportedIssues {
+		issues = issues[:maxReportedIssues]
+		out.Truncated = true
+	}
+	out.Issues = append(out.Issues, issues...)
+	return out
+}
+
+// reasonKey groups reasons that name a row, e.g. every duplicate id.
+func reasonKey(reason string) string {
+	key, _, _ := strings.Cut(reason, ",")
+	return key
+}